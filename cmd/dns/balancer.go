@@ -0,0 +1,130 @@
+package dns
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/balancer"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+)
+
+func (m *DNSManager) startTunnelPool(tunnel DNSTunnel) error {
+	if len(tunnel.Backends) == 0 {
+		return nil
+	}
+
+	backends := make([]*balancer.Backend, 0, len(tunnel.Backends))
+	for _, b := range tunnel.Backends {
+		backends = append(backends, &balancer.Backend{
+			Name: b.Pod,
+			Addr: fmt.Sprintf("localhost:%d", b.LocalPort),
+		})
+	}
+
+	dnsURL := tunnel.DNSURL
+	pool := balancer.NewPool(m.policy, backends, func(b *balancer.Backend) {
+		m.removeTunnelBackend(dnsURL, b.Name)
+	})
+	listeners, err := m.ports.Take(tunnel.LocalPort)
+	if err != nil {
+		return fmt.Errorf("start load balancer for %s: %w", dnsURL, err)
+	}
+	pool.Serve(listeners...)
+
+	for i, b := range tunnel.Backends {
+		if err := m.addPodHost(tunnel, b); err != nil {
+			for _, added := range tunnel.Backends[:i] {
+				m.removePodHost(tunnel, added)
+			}
+			pool.Close()
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.pools[dnsURL] = pool
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *DNSManager) stopTunnelForwards(tunnel DNSTunnel) error {
+	m.mu.Lock()
+	pool, ok := m.pools[tunnel.DNSURL]
+	delete(m.pools, tunnel.DNSURL)
+	m.mu.Unlock()
+
+	if ok {
+		pool.Close()
+	}
+	if ok || len(tunnel.Backends) > 0 {
+		m.ports.Release(tunnel.LocalPort)
+	}
+	for _, b := range tunnel.Backends {
+		m.removePodHost(tunnel, b)
+	}
+
+	if tunnel.Pod == "" {
+		return nil
+	}
+	if tunnel.Relay {
+		m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.RelayNamespace, tunnel.Pod, tunnel.RemotePort)
+		return m.kubeAdapter.DeleteRelay(tunnel.Context, tunnel.RelayNamespace, tunnel.Pod)
+	}
+	if len(tunnel.Backends) == 0 {
+		return m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
+	}
+
+	for _, b := range tunnel.Backends {
+		m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, b.Pod, b.RemotePort)
+	}
+	return nil
+}
+
+func (m *DNSManager) stopStartedForwards(tunnel kube.ServiceTunnel) {
+	if len(tunnel.Backends) == 0 {
+		if tunnel.Pod != "" {
+			m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
+		}
+		return
+	}
+
+	for _, b := range tunnel.Backends {
+		m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, b.Pod, b.RemotePort)
+	}
+	m.ports.Release(tunnel.LocalPort)
+}
+
+func (m *DNSManager) removeTunnelBackend(dnsURL, pod string) {
+	m.mu.Lock()
+	var updated DNSTunnel
+	var removed kube.PodForward
+	found := false
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL != dnsURL {
+			continue
+		}
+		for j, b := range t.Backends {
+			if b.Pod != pod {
+				continue
+			}
+			removed = b
+			found = true
+			m.dnsTunnels[i].Backends = slices.Delete(slices.Clone(t.Backends), j, j+1)
+			if len(m.dnsTunnels[i].Backends) > 0 {
+				m.dnsTunnels[i].Pod = m.dnsTunnels[i].Backends[0].Pod
+			}
+			updated = m.dnsTunnels[i]
+			break
+		}
+		break
+	}
+	m.mu.Unlock()
+
+	if !found {
+		return
+	}
+
+	m.removePodHost(updated, removed)
+	m.kubeAdapter.UnregisterServicePortForward(updated.Context, updated.Namespace, removed.Pod, removed.RemotePort)
+	m.publishTunnel(TunnelEventUpdated, updated, nil)
+}
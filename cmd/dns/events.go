@@ -0,0 +1,151 @@
+package dns
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+type TunnelEventType string
+
+const subscriberBacklog = 256
+
+const (
+	TunnelEventRegistering  TunnelEventType = "registering"
+	TunnelEventReady        TunnelEventType = "ready"
+	TunnelEventUpdated      TunnelEventType = "updated"
+	TunnelEventIdle         TunnelEventType = "idle"
+	TunnelEventDegraded     TunnelEventType = "degraded"
+	TunnelEventReconnecting TunnelEventType = "reconnecting"
+	TunnelEventRemoved      TunnelEventType = "removed"
+	TunnelEventError        TunnelEventType = "error"
+	TunnelEventWarning      TunnelEventType = "warning"
+)
+
+type TunnelEvent struct {
+	Type      TunnelEventType
+	Time      time.Time
+	Context   string
+	Namespace string
+	Name      string
+	DNSURL    string
+	Pod       string
+	State     string
+	Tunnel    DNSTunnel
+	Err       error
+}
+
+type eventSubscriber struct {
+	events  chan TunnelEvent
+	pending []TunnelEvent
+	wake    chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex
+}
+
+func (m *DNSManager) Subscribe() (<-chan TunnelEvent, func()) {
+	sub := &eventSubscriber{
+		events: make(chan TunnelEvent),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	m.eventMu.Lock()
+	m.subscribers[sub] = struct{}{}
+	m.eventMu.Unlock()
+
+	go sub.run()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			m.eventMu.Lock()
+			delete(m.subscribers, sub)
+			m.eventMu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+func (m *DNSManager) publish(event TunnelEvent) {
+	event.Time = time.Now()
+
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+	for sub := range m.subscribers {
+		sub.push(event)
+	}
+}
+
+func (m *DNSManager) publishTunnel(eventType TunnelEventType, tunnel DNSTunnel, err error) {
+	tunnel.Capturing = m.proxyAdapter.IsCapturing(tunnel.DNSURL)
+	m.publish(TunnelEvent{
+		Type:      eventType,
+		Context:   tunnel.Context,
+		Namespace: tunnel.Namespace,
+		Name:      tunnel.ServiceName,
+		DNSURL:    tunnel.DNSURL,
+		Pod:       tunnel.Pod,
+		State:     tunnel.State,
+		Tunnel:    tunnel,
+		Err:       err,
+	})
+}
+
+func (m *DNSManager) publishFailure(event TunnelEvent, err error) {
+	if err == nil {
+		return
+	}
+	event.Type = TunnelEventError
+	event.Err = err
+	m.publish(event)
+}
+
+func (m *DNSManager) publishWarning(event TunnelEvent, err error) {
+	if err == nil {
+		return
+	}
+	event.Type = TunnelEventWarning
+	event.Err = err
+	m.publish(event)
+}
+
+func (s *eventSubscriber) push(event TunnelEvent) {
+	s.mu.Lock()
+	if len(s.pending) >= subscriberBacklog {
+		s.pending = slices.Delete(s.pending, 0, len(s.pending)-subscriberBacklog+1)
+	}
+	s.pending = append(s.pending, event)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *eventSubscriber) run() {
+	defer close(s.events)
+
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		event := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package dns
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newEventManager() *DNSManager {
+	return &DNSManager{subscribers: make(map[*eventSubscriber]struct{})}
+}
+
+func receiveEvent(t *testing.T, events <-chan TunnelEvent) TunnelEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return TunnelEvent{}
+}
+
+func TestPublishFansOutToEverySubscriber(t *testing.T) {
+	m := newEventManager()
+	first, unsubscribeFirst := m.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := m.Subscribe()
+	defer unsubscribeSecond()
+
+	m.publish(TunnelEvent{Type: TunnelEventReady, DNSURL: "api.default"})
+	m.publish(TunnelEvent{Type: TunnelEventRemoved, DNSURL: "api.default"})
+
+	for _, events := range []<-chan TunnelEvent{first, second} {
+		for _, want := range []TunnelEventType{TunnelEventReady, TunnelEventRemoved} {
+			event := receiveEvent(t, events)
+			if event.Type != want || event.DNSURL != "api.default" {
+				t.Errorf("received %s for %s, want %s for api.default", event.Type, event.DNSURL, want)
+			}
+			if event.Time.IsZero() {
+				t.Error("expected the event time to be set")
+			}
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	m := newEventManager()
+	events, unsubscribe := m.Subscribe()
+
+	unsubscribe()
+	unsubscribe()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected no events after unsubscribing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the channel to close")
+	}
+
+	m.publish(TunnelEvent{Type: TunnelEventReady})
+	if len(m.subscribers) != 0 {
+		t.Errorf("expected no subscribers left, got %d", len(m.subscribers))
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockPublish(t *testing.T) {
+	m := newEventManager()
+	events, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range subscriberBacklog * 4 {
+			m.publish(TunnelEvent{Type: TunnelEventUpdated})
+		}
+		m.publish(TunnelEvent{Type: TunnelEventRemoved})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a subscriber that is not reading")
+	}
+
+	received := 0
+	for {
+		event := receiveEvent(t, events)
+		received++
+		if event.Type == TunnelEventRemoved {
+			break
+		}
+	}
+	if received > subscriberBacklog+1 {
+		t.Errorf("received %d events, want at most %d", received, subscriberBacklog+1)
+	}
+}
+
+func TestPendingEventsAreCapped(t *testing.T) {
+	sub := &eventSubscriber{wake: make(chan struct{}, 1)}
+
+	for i := range subscriberBacklog + 10 {
+		sub.push(TunnelEvent{Pod: strconv.Itoa(i)})
+	}
+
+	if len(sub.pending) != subscriberBacklog {
+		t.Fatalf("pending events = %d, want %d", len(sub.pending), subscriberBacklog)
+	}
+	if first := sub.pending[0].Pod; first != "10" {
+		t.Errorf("oldest kept event = %s, want 10", first)
+	}
+	if last := sub.pending[len(sub.pending)-1].Pod; last != strconv.Itoa(subscriberBacklog+9) {
+		t.Errorf("newest kept event = %s, want %d", last, subscriberBacklog+9)
+	}
+}
+
+func TestPublishFailureAndWarning(t *testing.T) {
+	m := newEventManager()
+	events, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+
+	m.publishFailure(TunnelEvent{Context: "prod"}, nil)
+	m.publishWarning(TunnelEvent{Context: "prod"}, nil)
+	m.publishFailure(TunnelEvent{Type: TunnelEventRegistering, Context: "prod"}, errors.New("boom"))
+	m.publishWarning(TunnelEvent{Context: "prod"}, errors.New("careful"))
+
+	if event := receiveEvent(t, events); event.Type != TunnelEventError || event.Err == nil || event.Context != "prod" {
+		t.Errorf("unexpected failure event %+v", event)
+	}
+	if event := receiveEvent(t, events); event.Type != TunnelEventWarning || event.Err == nil {
+		t.Errorf("unexpected warning event %+v", event)
+	}
+}
@@ -0,0 +1,145 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+)
+
+const TunnelStateExternal = "external"
+
+func (m *DNSManager) registerExternalTunnel(ctx context.Context, tunnel kube.ServiceTunnel) (DNSTunnel, error) {
+	if _, exists := m.tunnelsByDNSURL()[tunnel.DNSURL]; exists {
+		return DNSTunnel{}, fmt.Errorf("tunnel already registered: %s", tunnel.DNSURL)
+	}
+
+	address, err := resolveExternalName(ctx, tunnel.ExternalName)
+	if m.relay && ctx.Err() == nil && (err != nil || isPrivateAddress(address)) {
+		return m.registerRelayTunnel(ctx, tunnel)
+	}
+	if err != nil {
+		return DNSTunnel{}, err
+	}
+
+	dnsTunnel := DNSTunnel{
+		Context:      tunnel.Context,
+		Namespace:    tunnel.Namespace,
+		DNSURL:       tunnel.DNSURL,
+		ServiceName:  tunnel.ServiceName,
+		ExternalName: tunnel.ExternalName,
+		Address:      address,
+		State:        TunnelStateExternal,
+	}
+
+	if err := m.hostsFileAdapter.AddEntryWithIP(dnsTunnel.DNSURL, address); err != nil {
+		return DNSTunnel{}, fmt.Errorf("add hosts entry: %w", err)
+	}
+
+	m.addTunnels([]DNSTunnel{dnsTunnel})
+	return dnsTunnel, nil
+}
+
+func resolveExternalName(ctx context.Context, host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("resolve external name %s: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			return ip4.String(), nil
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs[0].IP.String(), nil
+	}
+	return "", fmt.Errorf("resolve external name %s: no addresses found", host)
+}
+
+func (m *DNSManager) registerRelayTunnel(ctx context.Context, tunnel kube.ServiceTunnel) (DNSTunnel, error) {
+	if tunnel.ServicePort == 0 {
+		return DNSTunnel{}, fmt.Errorf("ExternalName service %s/%s has no ports to relay", tunnel.Namespace, tunnel.ServiceName)
+	}
+
+	httpPort := tunnel.ServicePort == 80
+	localPort := tunnel.ServicePort
+	if httpPort {
+		localPort = 0
+		if err := m.startProxy(); err != nil {
+			return DNSTunnel{}, err
+		}
+	}
+
+	relay, err := m.kubeAdapter.StartRelay(ctx, tunnel.Context, tunnel.Namespace, tunnel.ExternalName, tunnel.ServicePort, localPort, m.getUsedPorts())
+	if err != nil {
+		if ctx.Err() != nil {
+			return DNSTunnel{}, ctx.Err()
+		}
+		return DNSTunnel{}, fmt.Errorf("start relay: %w", err)
+	}
+
+	dnsTunnel := DNSTunnel{
+		Context:        tunnel.Context,
+		Namespace:      tunnel.Namespace,
+		DNSURL:         tunnel.DNSURL,
+		ServiceName:    tunnel.ServiceName,
+		ServicePort:    tunnel.ServicePort,
+		Pod:            relay.Pod,
+		LocalPort:      relay.LocalPort,
+		RemotePort:     relay.RemotePort,
+		Transport:      relay.Transport,
+		Protocol:       normalizeProtocol(""),
+		State:          TunnelStateActive,
+		ExternalName:   tunnel.ExternalName,
+		Relay:          true,
+		RelayNamespace: relay.Namespace,
+	}
+	if ctx.Err() != nil {
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, ctx.Err()
+	}
+
+	if httpPort {
+		m.proxyAdapter.AddRoute(dnsTunnel.DNSURL, dnsTunnel.LocalPort)
+	}
+
+	if err := m.hostsFileAdapter.AddEntry(dnsTunnel.DNSURL); err != nil {
+		m.proxyAdapter.RemoveRoute(dnsTunnel.DNSURL)
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, fmt.Errorf("add hosts entry: %w", err)
+	}
+
+	m.addTunnels([]DNSTunnel{dnsTunnel})
+	return dnsTunnel, nil
+}
+
+func isPrivateAddress(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsPrivate()
+}
+
+func (m *DNSManager) collectOrphanRelays(contextName string, services []kube.Service) {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, svc := range services {
+		if svc.IsExternalName() && !seen[svc.Namespace] {
+			seen[svc.Namespace] = true
+			namespaces = append(namespaces, svc.Namespace)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := m.kubeAdapter.CollectOrphanRelays(ctx, contextName, namespaces)
+	m.publishWarning(TunnelEvent{Context: contextName}, err)
+}
@@ -0,0 +1,231 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/balancer"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
+
+const headlessSyncInterval = 15 * time.Second
+
+type podAddress struct {
+	address string
+	users   int
+	pools   map[int32]*balancer.Pool
+}
+
+func (m *DNSManager) addPodHost(tunnel DNSTunnel, forward kube.PodForward) error {
+	if forward.Host == "" {
+		return nil
+	}
+
+	m.proxyAdapter.AddRoute(forward.Host, forward.LocalPort)
+	if protocol, err := proxyadapter.ParseProtocol(tunnel.Protocol); err == nil && protocol != proxyadapter.ProtocolHTTP1 {
+		m.proxyAdapter.SetRouteProtocol(forward.Host, protocol)
+	}
+
+	podHost := kube.BuildPodDNS(forward.Pod, tunnel.ServiceName, tunnel.Namespace, 80)
+	if podHost != forward.Host {
+		if err := m.hostsFileAdapter.AddEntry(forward.Host); err != nil {
+			m.proxyAdapter.RemoveRoute(forward.Host)
+			return fmt.Errorf("add hosts entry: %w", err)
+		}
+	}
+
+	if err := m.bindPodAddress(tunnel, podHost, forward); err != nil {
+		m.proxyAdapter.RemoveRoute(forward.Host)
+		if podHost != forward.Host {
+			m.hostsFileAdapter.RemoveEntry(forward.Host)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *DNSManager) removePodHost(tunnel DNSTunnel, forward kube.PodForward) {
+	if forward.Host == "" {
+		return
+	}
+
+	m.proxyAdapter.RemoveRoute(forward.Host)
+	podHost := kube.BuildPodDNS(forward.Pod, tunnel.ServiceName, tunnel.Namespace, 80)
+	if podHost != forward.Host {
+		m.hostsFileAdapter.RemoveEntry(forward.Host)
+	}
+	m.releasePodAddress(podHost, tunnel.ServicePort)
+}
+
+func (m *DNSManager) bindPodAddress(tunnel DNSTunnel, podHost string, forward kube.PodForward) error {
+	m.podMu.Lock()
+	defer m.podMu.Unlock()
+
+	entry, ok := m.podAddresses[podHost]
+	if !ok {
+		address, err := m.nextPodAddressLocked()
+		if err != nil {
+			return err
+		}
+		entry = &podAddress{address: address, pools: make(map[int32]*balancer.Pool)}
+	}
+
+	servicePort := tunnel.ServicePort
+	var pool *balancer.Pool
+	if _, bound := entry.pools[servicePort]; !bound && servicePort != 80 && servicePort != 443 {
+		listener, err := net.Listen("tcp", net.JoinHostPort(entry.address, strconv.Itoa(int(servicePort))))
+		if err != nil {
+			m.publishWarning(TunnelEvent{Context: tunnel.Context, Namespace: tunnel.Namespace, Name: tunnel.ServiceName, DNSURL: tunnel.DNSURL, Pod: forward.Pod}, fmt.Errorf("%s:%d is not available, use %s instead: %w", podHost, servicePort, forward.Host, err))
+		} else {
+			pool = balancer.NewPool(m.policy, []*balancer.Backend{{
+				Name: forward.Pod,
+				Addr: fmt.Sprintf("localhost:%d", forward.LocalPort),
+			}}, nil)
+			pool.Serve(listener)
+		}
+	}
+
+	if !ok {
+		if err := m.hostsFileAdapter.AddEntryWithIP(podHost, entry.address); err != nil {
+			if pool != nil {
+				pool.Close()
+			}
+			return fmt.Errorf("add hosts entry: %w", err)
+		}
+		m.podAddresses[podHost] = entry
+	}
+	entry.users++
+	if pool != nil {
+		entry.pools[servicePort] = pool
+	}
+	return nil
+}
+
+func (m *DNSManager) releasePodAddress(podHost string, servicePort int32) {
+	m.podMu.Lock()
+	defer m.podMu.Unlock()
+
+	entry, ok := m.podAddresses[podHost]
+	if !ok {
+		return
+	}
+	if pool, bound := entry.pools[servicePort]; bound {
+		pool.Close()
+		delete(entry.pools, servicePort)
+	}
+	entry.users--
+	if entry.users > 0 {
+		return
+	}
+	delete(m.podAddresses, podHost)
+	m.hostsFileAdapter.RemoveEntry(podHost)
+}
+
+func (m *DNSManager) nextPodAddressLocked() (string, error) {
+	used := make(map[string]bool, len(m.podAddresses))
+	for _, entry := range m.podAddresses {
+		used[entry.address] = true
+	}
+	for i := 0; i < 256*254; i++ {
+		address := fmt.Sprintf("127.1.%d.%d", i/254, i%254+1)
+		if !used[address] {
+			return address, nil
+		}
+	}
+	return "", fmt.Errorf("no free loopback address for pod hostnames")
+}
+
+func (m *DNSManager) closePodAddresses() {
+	m.podMu.Lock()
+	defer m.podMu.Unlock()
+
+	for podHost, entry := range m.podAddresses {
+		for _, pool := range entry.pools {
+			pool.Close()
+		}
+		delete(m.podAddresses, podHost)
+	}
+}
+
+func (m *DNSManager) runHeadlessSync(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, tunnel := range m.GetAllDNSTunnels() {
+			if !tunnel.Headless || (tunnel.State != TunnelStateActive && tunnel.State != TunnelStateDegraded) {
+				continue
+			}
+			m.syncHeadlessTunnel(tunnel)
+		}
+	}
+}
+
+func (m *DNSManager) syncHeadlessTunnel(tunnel DNSTunnel) {
+	added, removed, err := m.kubeAdapter.SyncHeadlessForwards(m.ctx, tunnel.Context, tunnel.ServiceName, tunnel.Namespace, tunnel.ServicePort, tunnel.Backends, m.getUsedPorts())
+	if err != nil {
+		return
+	}
+
+	m.mu.RLock()
+	pool, ok := m.pools[tunnel.DNSURL]
+	m.mu.RUnlock()
+
+	for _, forward := range added {
+		if !ok || !m.addTunnelBackend(tunnel.DNSURL, forward) {
+			m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, forward.Pod, forward.RemotePort)
+			continue
+		}
+		m.addPodHost(tunnel, forward)
+		pool.Add(&balancer.Backend{
+			Name: forward.Pod,
+			Addr: fmt.Sprintf("localhost:%d", forward.LocalPort),
+		})
+	}
+
+	if !ok {
+		return
+	}
+	if len(added) > 0 {
+		recovered := false
+		if tunnel.State == TunnelStateDegraded {
+			var updated DNSTunnel
+			if updated, recovered = m.setTunnelState(tunnel.DNSURL, TunnelStateActive); recovered {
+				m.publishTunnel(TunnelEventReady, updated, nil)
+			}
+		}
+		if current, found := m.tunnelsByDNSURL()[tunnel.DNSURL]; found && !recovered {
+			m.publishTunnel(TunnelEventUpdated, current, nil)
+		}
+	}
+	for _, forward := range removed {
+		for _, b := range pool.Backends() {
+			if b.Name == forward.Pod {
+				pool.Remove(b)
+			}
+		}
+	}
+}
+
+func (m *DNSManager) addTunnelBackend(dnsURL string, forward kube.PodForward) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL == dnsURL {
+			m.dnsTunnels[i].Backends = append(slices.Clone(t.Backends), forward)
+			return true
+		}
+	}
+	return false
+}
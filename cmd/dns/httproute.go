@@ -0,0 +1,140 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
+
+func (m *DNSManager) RegisterHTTPRoute(ctx context.Context, contextName, namespace, name string) ([]string, error) {
+	if contextName == "" || namespace == "" || name == "" {
+		return nil, fmt.Errorf("context name, namespace and HTTPRoute name are required")
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	routes, err := m.kubeAdapter.ListHTTPRoutes(listCtx, namespace, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("list httproutes: %w", err)
+	}
+
+	var route *kube.HTTPRoute
+	for i := range routes {
+		if routes[i].Name == name {
+			route = &routes[i]
+			break
+		}
+	}
+	if route == nil {
+		return nil, fmt.Errorf("httproute %s/%s not found", namespace, name)
+	}
+
+	var skipped []string
+	var created []string
+	var paths []VirtualHostPath
+	seen := make(map[string]bool)
+
+	for _, rule := range route.Rules {
+		var backends []VirtualHostBackend
+		skippedBackend := false
+		for _, backend := range rule.Backends {
+			if backend.Weight <= 0 {
+				continue
+			}
+			dnsURL, fresh, err := m.ensureServiceTunnel(ctx, contextName, backend.Namespace, backend.ServiceName, backend.Port)
+			if fresh {
+				created = append(created, dnsURL)
+			}
+			if ctx.Err() != nil {
+				m.rollbackTunnels(created)
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("backend %s/%s:%d: %v", backend.Namespace, backend.ServiceName, backend.Port, err))
+				skippedBackend = true
+				continue
+			}
+			backends = append(backends, VirtualHostBackend{Tunnel: dnsURL, Weight: backend.Weight})
+		}
+		if len(backends) == 0 {
+			if len(rule.Backends) > 0 && !skippedBackend {
+				skipped = append(skipped, "rule with only zero-weight backends")
+			}
+			continue
+		}
+
+		for _, match := range rule.Matches {
+			if match.PathType == "RegularExpression" {
+				skipped = append(skipped, fmt.Sprintf("regular expression path %s", match.Path))
+				continue
+			}
+			if match.Method != "" {
+				skipped = append(skipped, fmt.Sprintf("path %s: method match %s is not supported", match.Path, match.Method))
+				continue
+			}
+			if len(match.QueryParams) > 0 {
+				skipped = append(skipped, fmt.Sprintf("path %s: query parameter match on %s is not supported", match.Path, strings.Join(match.QueryParams, ", ")))
+				continue
+			}
+
+			exact := match.PathType == "Exact"
+			path := VirtualHostPath{
+				Prefix:   proxyadapter.NormalizePath(match.Path, exact),
+				Exact:    exact,
+				Backends: backends,
+			}
+			for _, header := range match.Headers {
+				path.Headers = append(path.Headers, proxyadapter.HeaderMatch{Name: header.Name, Value: header.Value, Regex: header.Regex})
+			}
+
+			key := path.match().Key()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		m.rollbackTunnels(created)
+		if len(skipped) == 0 {
+			return nil, fmt.Errorf("httproute %s/%s has no service backends", namespace, name)
+		}
+		return nil, fmt.Errorf("no routable rules in httproute %s/%s: %s", namespace, name, strings.Join(skipped, "; "))
+	}
+
+	source := fmt.Sprintf("httproute %s/%s", namespace, name)
+	registered := 0
+	used := make(map[string]bool)
+	for _, hostname := range route.Hostnames {
+		if strings.Contains(hostname, "*") {
+			skipped = append(skipped, fmt.Sprintf("hostname %q cannot be resolved locally", hostname))
+			continue
+		}
+		if err := m.registerVirtualHost(VirtualHost{Host: hostname, Paths: paths, Source: source}, true); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", hostname, err))
+			continue
+		}
+		registered++
+		for _, path := range paths {
+			for _, backend := range path.Backends {
+				used[backend.Tunnel] = true
+			}
+		}
+	}
+	m.rollbackUnusedTunnels(created, used)
+
+	if registered == 0 {
+		if len(route.Hostnames) == 0 {
+			return nil, fmt.Errorf("httproute %s/%s has no hostnames", namespace, name)
+		}
+		return nil, fmt.Errorf("no hostnames of httproute %s/%s could be registered: %s", namespace, name, strings.Join(skipped, "; "))
+	}
+	return skipped, nil
+}
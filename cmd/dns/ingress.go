@@ -0,0 +1,126 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
+
+func (m *DNSManager) RegisterIngress(ctx context.Context, contextName, namespace, name string) ([]string, error) {
+	if contextName == "" || namespace == "" || name == "" {
+		return nil, fmt.Errorf("context name, namespace and ingress name are required")
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	ingresses, err := m.kubeAdapter.ListIngresses(listCtx, namespace, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("list ingresses: %w", err)
+	}
+
+	var ingress *kube.Ingress
+	for i := range ingresses {
+		if ingresses[i].Name == name {
+			ingress = &ingresses[i]
+			break
+		}
+	}
+	if ingress == nil {
+		return nil, fmt.Errorf("ingress %s/%s not found", namespace, name)
+	}
+
+	source := fmt.Sprintf("ingress %s/%s", namespace, name)
+	var skipped []string
+	var created []string
+	var hosts []VirtualHost
+
+	for _, rule := range ingress.Rules {
+		if rule.Host == "" || strings.Contains(rule.Host, "*") {
+			skipped = append(skipped, fmt.Sprintf("host %q cannot be resolved locally", rule.Host))
+			continue
+		}
+
+		vh := VirtualHost{Host: rule.Host, Source: source}
+		seen := make(map[string]bool, len(rule.Paths))
+		for _, path := range rule.Paths {
+			exact := path.PathType == "Exact"
+			prefix := proxyadapter.NormalizePath(path.Path, exact)
+			key := proxyadapter.PathMatch{Path: prefix, Exact: exact}.Key()
+			if seen[key] {
+				continue
+			}
+
+			dnsURL, fresh, err := m.ensureServiceTunnel(ctx, contextName, namespace, path.ServiceName, path.ServicePort)
+			if fresh {
+				created = append(created, dnsURL)
+			}
+			if ctx.Err() != nil {
+				m.rollbackTunnels(created)
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s%s: %v", rule.Host, prefix, err))
+				continue
+			}
+			seen[key] = true
+			vh.Paths = append(vh.Paths, VirtualHostPath{Prefix: prefix, Exact: exact, Tunnel: dnsURL})
+		}
+		if len(vh.Paths) > 0 {
+			hosts = append(hosts, vh)
+		}
+	}
+
+	registered := 0
+	used := make(map[string]bool)
+	for _, vh := range hosts {
+		if err := m.registerVirtualHost(vh, true); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", vh.Host, err))
+			continue
+		}
+		registered++
+		for _, path := range vh.Paths {
+			used[path.Tunnel] = true
+		}
+	}
+	m.rollbackUnusedTunnels(created, used)
+
+	if registered == 0 {
+		if len(skipped) == 0 {
+			return nil, fmt.Errorf("ingress %s/%s has no service backends", namespace, name)
+		}
+		return nil, fmt.Errorf("no routable rules in ingress %s/%s: %s", namespace, name, strings.Join(skipped, "; "))
+	}
+	return skipped, nil
+}
+
+func (m *DNSManager) ensureServiceTunnel(ctx context.Context, contextName, namespace, serviceName string, servicePort int32) (string, bool, error) {
+	dnsURL := kube.BuildServiceDNS(serviceName, namespace, servicePort)
+	if _, exists := m.tunnelsByDNSURL()[dnsURL]; exists {
+		return dnsURL, false, nil
+	}
+
+	tunnel, err := m.registerServiceTunnel(ctx, contextName, serviceName, namespace, servicePort, "")
+	if err != nil {
+		return "", false, err
+	}
+	return tunnel.DNSURL, true, nil
+}
+
+func (m *DNSManager) rollbackTunnels(dnsURLs []string) {
+	for _, dnsURL := range dnsURLs {
+		m.UnregisterDNSTunnel(dnsURL)
+	}
+}
+
+func (m *DNSManager) rollbackUnusedTunnels(dnsURLs []string, used map[string]bool) {
+	for _, dnsURL := range dnsURLs {
+		if !used[dnsURL] {
+			m.UnregisterDNSTunnel(dnsURL)
+		}
+	}
+}
@@ -1,12 +1,33 @@
 package dns
 
-import "github.com/byoungmin/kube-service-tunnel/internal/kube"
+import (
+	"context"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
 
 type DNSManagerInterface interface {
+	KubeAdapter() kube.KubeAdapterInterface
 	GetAllDNSTunnels() []DNSTunnel
-	RegisterAllByContext(contextName string, services []kube.Service) error
-	RegisterDNSTunnel(contextName, serviceName, namespace string) error
+	RegisterAllByContext(ctx context.Context, contextName string, services []kube.Service) (RegistrationReport, error)
+	RegisterDNSTunnel(ctx context.Context, contextName, serviceName, namespace string) error
+	RegisterPinnedDNSTunnel(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, pod string) error
 	UnregisterDNSTunnel(dnsURL string) error
+	SetTunnelProtocol(dnsURL, protocol string) error
+	GetTunnelRequests(dnsURL string) []proxyadapter.Exchange
+	ReplayTunnelRequest(dnsURL string, id uint64) (int, error)
+	SetTunnelCapture(dnsURL string, enabled bool) (string, error)
+	GetAllVirtualHosts() []VirtualHost
+	RegisterVirtualHost(vh VirtualHost) error
+	UnregisterVirtualHost(host string) error
+	RegisterIngress(ctx context.Context, contextName, namespace, name string) ([]string, error)
+	RegisterHTTPRoute(ctx context.Context, contextName, namespace, name string) ([]string, error)
+	RegisterWorkloadTunnel(ctx context.Context, contextName, namespace, kind, name string) ([]string, error)
+	RegisterPodTunnel(ctx context.Context, contextName, namespace, pod string) ([]string, error)
+	ContextTransport(contextName string) string
+	SetContextTransport(contextName, transport string) error
+	Subscribe() (<-chan TunnelEvent, func())
 	Cleanup() error
 }
 
@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+)
+
+func (m *DNSManager) registerLazyTunnels(ctx context.Context, contextName string, services []kube.Service) (RegistrationReport, error) {
+	planned, results, err := m.kubeAdapter.PlanServiceTunnels(ctx, contextName, services)
+	if err != nil {
+		return RegistrationReport{Context: contextName}, err
+	}
+	if ctx.Err() != nil {
+		return RegistrationReport{Context: contextName}, ctx.Err()
+	}
+
+	if err := m.startProxy(); err != nil {
+		return RegistrationReport{Context: contextName}, err
+	}
+
+	report := RegistrationReport{Context: contextName, Results: results}
+	existing := m.tunnelsByDNSURL()
+	report.skipExisting(existing)
+	dnsTunnels := make([]DNSTunnel, 0, len(planned))
+	for _, tunnel := range planned {
+		if _, ok := existing[tunnel.DNSURL]; ok {
+			continue
+		}
+		dnsTunnel := convertToDNSTunnel(tunnel)
+		dnsTunnel.Lazy = true
+		dnsTunnel.State = TunnelStateIdle
+		dnsTunnels = append(dnsTunnels, dnsTunnel)
+	}
+
+	for _, dnsTunnel := range dnsTunnels {
+		m.proxyAdapter.AddLazyRoute(dnsTunnel.DNSURL, m.activateLazyTunnel)
+	}
+	m.addTunnels(dnsTunnels)
+
+	for i, dnsTunnel := range dnsTunnels {
+		if err := m.hostsFileAdapter.AddEntry(dnsTunnel.DNSURL); err != nil {
+			for j := 0; j < i; j++ {
+				m.hostsFileAdapter.RemoveEntry(dnsTunnels[j].DNSURL)
+			}
+			for _, added := range dnsTunnels {
+				m.removeTunnel(added.DNSURL)
+				m.proxyAdapter.RemoveRoute(added.DNSURL)
+			}
+			return RegistrationReport{Context: contextName}, fmt.Errorf("add hosts entry: %w", err)
+		}
+	}
+
+	m.syncVirtualHosts()
+	return report, nil
+}
+
+func (m *DNSManager) activateLazyTunnel(dnsURL string) error {
+	tunnel, ok := m.tunnelsByDNSURL()[dnsURL]
+	if !ok {
+		return fmt.Errorf("tunnel not found for DNS URL: %s", dnsURL)
+	}
+
+	started, err := m.kubeAdapter.RegisterServicePortForward(m.ctx, tunnel.Context, tunnel.ServiceName, tunnel.Namespace, tunnel.ServicePort, m.getUsedPorts())
+	if err != nil {
+		m.publishTunnel(TunnelEventError, tunnel, err)
+		return err
+	}
+
+	startedTunnel := convertToDNSTunnel(started)
+	if err := m.startTunnelPool(startedTunnel); err != nil {
+		m.stopTunnelForwards(startedTunnel)
+		m.publishTunnel(TunnelEventError, tunnel, err)
+		return err
+	}
+
+	m.mu.Lock()
+	index := -1
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL == dnsURL {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		m.mu.Unlock()
+		m.stopTunnelForwards(startedTunnel)
+		return fmt.Errorf("tunnel not found for DNS URL: %s", dnsURL)
+	}
+	m.dnsTunnels[index].Pod = started.Pod
+	m.dnsTunnels[index].LocalPort = started.LocalPort
+	m.dnsTunnels[index].RemotePort = started.RemotePort
+	m.dnsTunnels[index].Backends = started.Backends
+	m.dnsTunnels[index].State = TunnelStateActive
+	tunnel = m.dnsTunnels[index]
+	m.mu.Unlock()
+
+	m.proxyAdapter.AddRoute(tunnel.DNSURL, tunnel.LocalPort)
+	m.applyRouteProtocol(tunnel)
+	m.syncVirtualHosts()
+	m.publishTunnel(TunnelEventReady, tunnel, nil)
+	return nil
+}
+
+func (m *DNSManager) runIdleJanitor(idleTimeout time.Duration) {
+	interval := idleTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, tunnel := range m.GetAllDNSTunnels() {
+			if !tunnel.Lazy || tunnel.State != TunnelStateActive {
+				continue
+			}
+			m.proxyAdapter.DeactivateIdleRoute(tunnel.DNSURL, idleTimeout, m.deactivateLazyTunnel)
+		}
+	}
+}
+
+func (m *DNSManager) deactivateLazyTunnel(dnsURL string) {
+	m.mu.Lock()
+	var stopped, idle DNSTunnel
+	found := false
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL == dnsURL && t.State == TunnelStateActive {
+			stopped = t
+			found = true
+			m.dnsTunnels[i].Pod = ""
+			m.dnsTunnels[i].LocalPort = 0
+			m.dnsTunnels[i].RemotePort = 0
+			m.dnsTunnels[i].State = TunnelStateIdle
+			idle = m.dnsTunnels[i]
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if !found {
+		return
+	}
+
+	m.stopTunnelForwards(stopped)
+	m.syncVirtualHosts()
+	m.publishTunnel(TunnelEventIdle, idle, nil)
+}
@@ -1,22 +1,55 @@
 package dns
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/byoungmin/kube-service-tunnel/internal/balancer"
+	"github.com/byoungmin/kube-service-tunnel/internal/cert"
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
 	"github.com/byoungmin/kube-service-tunnel/internal/host"
 	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	"github.com/byoungmin/kube-service-tunnel/internal/ports"
 	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
 )
 
+const (
+	TunnelStateActive       = "active"
+	TunnelStateIdle         = "idle"
+	TunnelStateDegraded     = "degraded"
+	TunnelStateReconnecting = "reconnecting"
+	TunnelStateError        = "error"
+)
+
+const portMappingsFile = "ports.json"
+
 type DNSTunnel struct {
-	Context    string
-	Namespace  string
-	DNSURL     string
-	Pod        string
-	LocalPort  int32
-	RemotePort int32
+	Context        string
+	Namespace      string
+	DNSURL         string
+	ServiceName    string
+	ServicePort    int32
+	Kind           string
+	Pod            string
+	Pinned         bool
+	LocalPort      int32
+	RemotePort     int32
+	Protocol       string
+	Capturing      bool
+	Lazy           bool
+	Headless       bool
+	State          string
+	Backends       []kube.PodForward
+	ExternalName   string
+	Address        string
+	Relay          bool
+	RelayNamespace string
+	Transport      string
+	upstream       *proxyadapter.Upstream
 }
 
 type DNSManager struct {
@@ -25,50 +58,250 @@ type DNSManager struct {
 	hostsFileAdapter host.HostsFileAdapterInterface
 	proxyAdapter     proxyadapter.ProxyAdapterInterface
 	dnsTunnels       []DNSTunnel
+	virtualHosts     []*virtualHostState
+	lazy             config.LazyConfig
+	relay            bool
+	policy           balancer.Policy
+	pools            map[string]*balancer.Pool
+	podAddresses     map[string]*podAddress
+	ports            ports.AllocatorInterface
+	transports       map[string]string
+	subscribers      map[*eventSubscriber]struct{}
+	eventMu          sync.Mutex
+	podMu            sync.Mutex
+	ctx              context.Context
+	cancel           context.CancelFunc
 	mu               sync.RWMutex
+	vhMu             sync.Mutex
 }
 
-func NewDNSManager(kubeconfigPath string) (*DNSManager, error) {
-	kubeAdapter, err := kube.NewKubeAdapter(kubeconfigPath)
+func NewDNSManager(kubeconfigPath string, cfg *config.Config) (*DNSManager, error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeOptions := kube.Options{Replicas: 1}
+	policy := balancer.PolicyRoundRobin
+	var portRange config.PortsConfig
+	if cfg != nil {
+		parsed, err := balancer.ParsePolicy(cfg.LoadBalancing.Policy)
+		if err != nil {
+			return nil, err
+		}
+		policy = parsed
+		kubeOptions.Replicas = cfg.LoadBalancing.Replicas
+		kubeOptions.ExcludeServiceTypes = cfg.Services.ExcludeTypes
+		kubeOptions.Relay = kube.RelayOptions{
+			Image:     cfg.Relay.Image,
+			Namespace: cfg.Relay.Namespace,
+			CPU:       cfg.Relay.CPU,
+			Memory:    cfg.Relay.Memory,
+			Labels:    cfg.Relay.Labels,
+		}
+		portRange = cfg.Ports
+	}
+
+	var dnsManager *DNSManager
+	allocator, err := ports.NewAllocator(portRange.Min, portRange.Max, filepath.Join(configDir, portMappingsFile), func(err error) {
+		dnsManager.publishWarning(TunnelEvent{}, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create port allocator: %w", err)
+	}
+	kubeOptions.Ports = allocator
+
+	kubeOptions.OnForwardLost = func(lost kube.LostForward) {
+		dnsManager.handleForwardLost(lost)
+	}
+	kubeOptions.OnRelayError = func(err error) {
+		dnsManager.publishWarning(TunnelEvent{}, err)
+	}
+
+	kubeAdapter, err := kube.NewKubeAdapter(kubeconfigPath, kubeOptions)
 	if err != nil {
 		return nil, fmt.Errorf("create kube adapter: %w", err)
 	}
 
-	dnsManager := &DNSManager{
+	authority, err := cert.NewAuthority(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate authority: %w", err)
+	}
+
+	proxyOptions := buildProxyOptions(cfg, configDir)
+	proxyOptions.OnCaptureError = func(host string, err error) {
+		dnsManager.publishWarning(TunnelEvent{DNSURL: host}, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dnsManager = &DNSManager{
 		kubeconfigPath:   kubeconfigPath,
 		kubeAdapter:      kubeAdapter,
 		hostsFileAdapter: host.NewHostsFileAdapter(),
-		proxyAdapter:     proxyadapter.NewProxyAdapter(),
+		proxyAdapter:     proxyadapter.NewProxyAdapter(authority, proxyOptions),
+		policy:           policy,
+		pools:            make(map[string]*balancer.Pool),
+		podAddresses:     make(map[string]*podAddress),
+		ports:            allocator,
+		transports:       make(map[string]string),
+		subscribers:      make(map[*eventSubscriber]struct{}),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	if cfg != nil {
+		dnsManager.lazy = cfg.Lazy
+		dnsManager.relay = cfg.Relay.Enabled
+		for name, context := range cfg.Contexts {
+			dnsManager.transports[name] = context.Transport
+		}
+	}
+	if dnsManager.lazy.Enabled && dnsManager.lazy.IdleTimeout > 0 {
+		go dnsManager.runIdleJanitor(time.Duration(dnsManager.lazy.IdleTimeout))
+	}
+	go dnsManager.runHeadlessSync(headlessSyncInterval)
+
+	if cfg != nil {
+		for _, vhConfig := range cfg.VirtualHosts {
+			vh := VirtualHost{Host: vhConfig.Host}
+			for _, path := range vhConfig.Paths {
+				vh.Paths = append(vh.Paths, VirtualHostPath{Prefix: path.Prefix, Tunnel: path.Tunnel})
+			}
+			state, err := newVirtualHostState(vh)
+			if err != nil {
+				return nil, fmt.Errorf("load virtual hosts: %w", err)
+			}
+			dnsManager.virtualHosts = append(dnsManager.virtualHosts, state)
+		}
 	}
 
 	return dnsManager, nil
 }
 
+func buildProxyOptions(cfg *config.Config, configDir string) proxyadapter.Options {
+	options := proxyadapter.DefaultOptions()
+	options.HAR.Dir = filepath.Join(configDir, "har")
+	if cfg == nil {
+		return options
+	}
+
+	if cfg.Inspector.Capacity > 0 {
+		options.Inspector.Capacity = cfg.Inspector.Capacity
+	}
+	options.Inspector.CaptureHeaders = cfg.Inspector.CaptureHeaders
+	options.Inspector.MaxBodyBytes = cfg.Inspector.MaxBodyBytes
+
+	if cfg.HAR.Dir != "" {
+		options.HAR.Dir = cfg.HAR.Dir
+	}
+	options.HAR.CaptureHosts = cfg.HAR.CaptureHosts
+	options.HAR.MaxBodyBytes = cfg.HAR.MaxBodyBytes
+	options.HAR.MaxEntries = cfg.HAR.MaxEntries
+	options.HAR.RedactHeaders = cfg.HAR.RedactHeaders
+
+	for _, rule := range cfg.Routes {
+		options.Rules = append(options.Rules, proxyadapter.RouteRule{
+			Host: rule.Host,
+			RequestHeaders: proxyadapter.HeaderRules{
+				Add:    rule.RequestHeaders.Add,
+				Remove: rule.RequestHeaders.Remove,
+			},
+			ResponseHeaders: proxyadapter.HeaderRules{
+				Add:    rule.ResponseHeaders.Add,
+				Remove: rule.ResponseHeaders.Remove,
+			},
+			HostRewrite: rule.HostRewrite,
+			StripPrefix: rule.StripPrefix,
+			AddPrefix:   rule.AddPrefix,
+			CORS:        rule.CORS,
+		})
+	}
+	return options
+}
+
+func (m *DNSManager) KubeAdapter() kube.KubeAdapterInterface {
+	return m.kubeAdapter
+}
+
 func (m *DNSManager) GetAllDNSTunnels() []DNSTunnel {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make([]DNSTunnel, len(m.dnsTunnels))
 	copy(result, m.dnsTunnels)
+	for i := range result {
+		result[i].Capturing = m.proxyAdapter.IsCapturing(result[i].DNSURL)
+	}
 	return result
 }
 
-func (m *DNSManager) RegisterAllByContext(contextName string, services []kube.Service) error {
+func (m *DNSManager) RegisterAllByContext(ctx context.Context, contextName string, services []kube.Service) (RegistrationReport, error) {
 	if contextName == "" {
-		return fmt.Errorf("context name is required")
+		return RegistrationReport{}, fmt.Errorf("context name is required")
+	}
+
+	event := TunnelEvent{Type: TunnelEventRegistering, Context: contextName}
+	m.publish(event)
+	report, err := m.registerAllByContext(ctx, contextName, services)
+	m.publishFailure(event, err)
+	return report, err
+}
+
+func (m *DNSManager) registerAllByContext(ctx context.Context, contextName string, services []kube.Service) (RegistrationReport, error) {
+	if m.relay {
+		go m.collectOrphanRelays(contextName, services)
+	}
+
+	if m.usesAPIServer(contextName) {
+		return m.registerProxyTunnels(ctx, contextName, services)
+	}
+
+	if m.lazy.Enabled {
+		return m.registerLazyTunnels(ctx, contextName, services)
 	}
 
 	usedPorts := m.getUsedPorts()
+	registered := make(map[string]bool)
+	for dnsURL := range m.tunnelsByDNSURL() {
+		registered[dnsURL] = true
+	}
 
-	tunnels, err := m.kubeAdapter.RegisterAllServicesForContext(contextName, usedPorts, services)
+	tunnels, results, err := m.kubeAdapter.RegisterAllServicesForContext(ctx, contextName, usedPorts, registered, services)
 	if err != nil {
-		return err
+		return RegistrationReport{Context: contextName}, err
+	}
+	if ctx.Err() != nil {
+		for _, tunnel := range tunnels {
+			m.stopStartedForwards(tunnel)
+		}
+		return RegistrationReport{Context: contextName}, ctx.Err()
 	}
 
-	if err := m.proxyAdapter.StartIfNotRunning(80); err != nil {
-		return fmt.Errorf("start proxy server: %w", err)
+	report := RegistrationReport{Context: contextName, Results: results}
+	existing := m.tunnelsByDNSURL()
+	report.skipExisting(existing)
+
+	var fresh []kube.ServiceTunnel
+	for _, tunnel := range tunnels {
+		if _, exists := existing[tunnel.DNSURL]; exists {
+			m.stopStartedForwards(tunnel)
+			continue
+		}
+		fresh = append(fresh, tunnel)
+	}
+	if len(fresh) == 0 {
+		return report, nil
 	}
 
+	if err := m.activateTunnels(fresh); err != nil {
+		return RegistrationReport{Context: contextName}, err
+	}
+
+	m.syncVirtualHosts()
+	return report, nil
+}
+
+func (m *DNSManager) activateTunnels(tunnels []kube.ServiceTunnel) error {
 	routes := make(map[string]int32, len(tunnels))
 	dnsTunnels := make([]DNSTunnel, 0, len(tunnels))
 	for _, tunnel := range tunnels {
@@ -76,8 +309,27 @@ func (m *DNSManager) RegisterAllByContext(contextName string, services []kube.Se
 		routes[tunnel.DNSURL] = tunnel.LocalPort
 	}
 
-	m.addTunnels(dnsTunnels)
+	if err := m.startProxy(); err != nil {
+		for _, dnsTunnel := range dnsTunnels {
+			m.stopTunnelForwards(dnsTunnel)
+		}
+		return err
+	}
+
+	for _, dnsTunnel := range dnsTunnels {
+		if err := m.startTunnelPool(dnsTunnel); err != nil {
+			for _, started := range dnsTunnels {
+				m.stopTunnelForwards(started)
+			}
+			return err
+		}
+	}
+
 	m.proxyAdapter.AddRoutes(routes)
+	for _, dnsTunnel := range dnsTunnels {
+		m.applyRouteProtocol(dnsTunnel)
+	}
+	m.addTunnels(dnsTunnels)
 
 	for i, tunnel := range tunnels {
 		if err := m.hostsFileAdapter.AddEntry(tunnel.DNSURL); err != nil {
@@ -87,7 +339,7 @@ func (m *DNSManager) RegisterAllByContext(contextName string, services []kube.Se
 			for _, dnsTunnel := range dnsTunnels {
 				m.removeTunnel(dnsTunnel.DNSURL)
 				m.proxyAdapter.RemoveRoute(dnsTunnel.DNSURL)
-				m.kubeAdapter.UnregisterServicePortForward(dnsTunnel.Context, dnsTunnel.Namespace, dnsTunnel.Pod, dnsTunnel.RemotePort)
+				m.stopTunnelForwards(dnsTunnel)
 			}
 			return fmt.Errorf("add hosts entry: %w", err)
 		}
@@ -96,35 +348,99 @@ func (m *DNSManager) RegisterAllByContext(contextName string, services []kube.Se
 	return nil
 }
 
-func (m *DNSManager) RegisterDNSTunnel(contextName, serviceName, namespace string) error {
+func (m *DNSManager) RegisterDNSTunnel(ctx context.Context, contextName, serviceName, namespace string) error {
+	if contextName == "" || serviceName == "" || namespace == "" {
+		return fmt.Errorf("context name, service name and namespace are required")
+	}
+
+	if _, err := m.registerServiceTunnel(ctx, contextName, serviceName, namespace, 0, ""); err != nil {
+		return err
+	}
+
+	m.syncVirtualHosts()
+	return nil
+}
+
+func (m *DNSManager) RegisterPinnedDNSTunnel(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, pod string) error {
 	if contextName == "" || serviceName == "" || namespace == "" {
 		return fmt.Errorf("context name, service name and namespace are required")
 	}
 
+	if _, err := m.registerServiceTunnel(ctx, contextName, serviceName, namespace, servicePort, pod); err != nil {
+		return err
+	}
+
+	m.syncVirtualHosts()
+	return nil
+}
+
+func (m *DNSManager) registerServiceTunnel(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, pod string) (DNSTunnel, error) {
+	event := TunnelEvent{Type: TunnelEventRegistering, Context: contextName, Namespace: namespace, Name: serviceName, Pod: pod}
+	if servicePort != 0 {
+		event.DNSURL = kube.BuildServiceDNS(serviceName, namespace, servicePort)
+	}
+	m.publish(event)
+
+	tunnel, err := m.startServiceTunnel(ctx, contextName, serviceName, namespace, servicePort, pod)
+	m.publishFailure(event, err)
+	return tunnel, err
+}
+
+func (m *DNSManager) startServiceTunnel(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, pod string) (DNSTunnel, error) {
+	if m.usesAPIServer(contextName) {
+		if pod != "" {
+			return DNSTunnel{}, fmt.Errorf("choosing a pod requires the %s transport", config.TransportPortForward)
+		}
+		return m.registerProxyTunnel(ctx, contextName, serviceName, namespace, servicePort)
+	}
+
 	usedPorts := m.getUsedPorts()
 
-	tunnel, err := m.kubeAdapter.RegisterServicePortForward(contextName, serviceName, namespace, usedPorts)
+	var tunnel kube.ServiceTunnel
+	var err error
+	if pod != "" {
+		tunnel, err = m.kubeAdapter.RegisterServicePodPortForward(ctx, contextName, serviceName, namespace, servicePort, pod, usedPorts)
+	} else {
+		tunnel, err = m.kubeAdapter.RegisterServicePortForward(ctx, contextName, serviceName, namespace, servicePort, usedPorts)
+	}
 	if err != nil {
-		return err
+		return DNSTunnel{}, err
 	}
 
-	if err := m.proxyAdapter.StartIfNotRunning(80); err != nil {
-		m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
-		return fmt.Errorf("start proxy server: %w", err)
+	if tunnel.ExternalName != "" {
+		return m.registerExternalTunnel(ctx, tunnel)
 	}
 
-	m.proxyAdapter.AddRoute(tunnel.DNSURL, tunnel.LocalPort)
+	dnsTunnel := convertToDNSTunnel(tunnel)
+	dnsTunnel.Pinned = pod != ""
+	if ctx.Err() != nil {
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, ctx.Err()
+	}
+
+	if err := m.startProxy(); err != nil {
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, err
+	}
+
+	if err := m.startTunnelPool(dnsTunnel); err != nil {
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, err
+	}
+
+	m.proxyAdapter.AddRoute(dnsTunnel.DNSURL, dnsTunnel.LocalPort)
+	m.applyRouteProtocol(dnsTunnel)
 
-	m.addTunnels([]DNSTunnel{convertToDNSTunnel(tunnel)})
+	m.addTunnels([]DNSTunnel{dnsTunnel})
 
 	if err := m.hostsFileAdapter.AddEntry(tunnel.DNSURL); err != nil {
 		m.removeTunnel(tunnel.DNSURL)
 		m.proxyAdapter.RemoveRoute(tunnel.DNSURL)
-		m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
-		return fmt.Errorf("add hosts entry: %w", err)
+		m.stopTunnelForwards(dnsTunnel)
+		return DNSTunnel{}, fmt.Errorf("add hosts entry: %w", err)
 	}
 
-	return nil
+	return dnsTunnel, nil
 }
 
 func (m *DNSManager) UnregisterDNSTunnel(dnsURL string) error {
@@ -137,7 +453,7 @@ func (m *DNSManager) UnregisterDNSTunnel(dnsURL string) error {
 		return fmt.Errorf("tunnel not found for DNS URL: %s", dnsURL)
 	}
 
-	if err := m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort); err != nil {
+	if err := m.stopTunnelForwards(tunnel); err != nil {
 		if !strings.Contains(err.Error(), "port forward not found") {
 			m.addTunnels([]DNSTunnel{tunnel})
 			return fmt.Errorf("stop port forward: %w", err)
@@ -148,14 +464,121 @@ func (m *DNSManager) UnregisterDNSTunnel(dnsURL string) error {
 
 	if err := m.hostsFileAdapter.RemoveEntry(dnsURL); err != nil {
 		m.addTunnels([]DNSTunnel{tunnel})
-		m.proxyAdapter.AddRoute(tunnel.DNSURL, tunnel.LocalPort)
+		if tunnel.Lazy {
+			m.proxyAdapter.AddLazyRoute(tunnel.DNSURL, m.activateLazyTunnel)
+		}
+		if tunnel.upstream != nil {
+			m.proxyAdapter.AddUpstreamRoute(tunnel.DNSURL, tunnel.upstream)
+		} else if tunnel.LocalPort != 0 {
+			m.proxyAdapter.AddRoute(tunnel.DNSURL, tunnel.LocalPort)
+			m.applyRouteProtocol(tunnel)
+		}
 		return fmt.Errorf("remove hosts entry: %w", err)
 	}
 
+	m.syncVirtualHosts()
 	return nil
 }
 
+func (m *DNSManager) SetTunnelProtocol(dnsURL, protocol string) error {
+	parsed, err := proxyadapter.ParseProtocol(protocol)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	index := -1
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL == dnsURL {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		m.mu.Unlock()
+		return fmt.Errorf("tunnel not found for DNS URL: %s", dnsURL)
+	}
+	if m.dnsTunnels[index].Transport == config.TransportAPIServer && parsed != proxyadapter.ProtocolHTTP1 {
+		m.mu.Unlock()
+		return fmt.Errorf("protocol %s is not supported through the %s transport", parsed, config.TransportAPIServer)
+	}
+	previous := m.dnsTunnels[index].Protocol
+	m.dnsTunnels[index].Protocol = string(parsed)
+	updated := m.dnsTunnels[index]
+	m.mu.Unlock()
+
+	if err := m.proxyAdapter.SetRouteProtocol(dnsURL, parsed); err != nil {
+		m.mu.Lock()
+		for i, t := range m.dnsTunnels {
+			if t.DNSURL == dnsURL {
+				m.dnsTunnels[i].Protocol = previous
+			}
+		}
+		m.mu.Unlock()
+		return fmt.Errorf("set route protocol: %w", err)
+	}
+	for _, b := range updated.Backends {
+		if b.Host != "" {
+			m.proxyAdapter.SetRouteProtocol(b.Host, parsed)
+		}
+	}
+
+	m.syncVirtualHosts()
+	m.publishTunnel(TunnelEventUpdated, updated, nil)
+	return nil
+}
+
+func (m *DNSManager) GetTunnelRequests(dnsURL string) []proxyadapter.Exchange {
+	return m.proxyAdapter.Exchanges(dnsURL)
+}
+
+func (m *DNSManager) ReplayTunnelRequest(dnsURL string, id uint64) (int, error) {
+	if dnsURL == "" {
+		return 0, fmt.Errorf("DNS URL is required")
+	}
+	return m.proxyAdapter.ReplayExchange(dnsURL, id)
+}
+
+func (m *DNSManager) SetTunnelCapture(dnsURL string, enabled bool) (string, error) {
+	if dnsURL == "" {
+		return "", fmt.Errorf("DNS URL is required")
+	}
+
+	if enabled {
+		path, err := m.proxyAdapter.StartCapture(dnsURL)
+		if err != nil {
+			return "", fmt.Errorf("start HAR capture: %w", err)
+		}
+		m.publishCaptureChange(dnsURL)
+		return path, nil
+	}
+
+	path, err := m.proxyAdapter.StopCapture(dnsURL)
+	m.publishCaptureChange(dnsURL)
+	if err != nil {
+		return path, fmt.Errorf("stop HAR capture: %w", err)
+	}
+	return path, nil
+}
+
+func (m *DNSManager) publishCaptureChange(dnsURL string) {
+	if tunnel, ok := m.tunnelsByDNSURL()[dnsURL]; ok {
+		m.publishTunnel(TunnelEventUpdated, tunnel, nil)
+	}
+}
+
 func (m *DNSManager) Cleanup() error {
+	m.cancel()
+	m.kubeAdapter.DeleteAllRelays()
+
+	m.mu.Lock()
+	for dnsURL, pool := range m.pools {
+		pool.Close()
+		delete(m.pools, dnsURL)
+	}
+	m.mu.Unlock()
+	m.closePodAddresses()
+
 	m.kubeAdapter.StopAllPortForwards()
 
 	if err := m.proxyAdapter.Stop(); err != nil {
@@ -166,12 +589,37 @@ func (m *DNSManager) Cleanup() error {
 		return fmt.Errorf("clear hosts file entries: %w", err)
 	}
 
+	m.resetVirtualHosts()
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	removed := m.dnsTunnels
 	m.dnsTunnels = []DNSTunnel{}
+	m.mu.Unlock()
+
+	for _, tunnel := range removed {
+		m.publishTunnel(TunnelEventRemoved, tunnel, nil)
+	}
 	return nil
 }
 
+func (m *DNSManager) startProxy() error {
+	if err := m.proxyAdapter.StartIfNotRunning(80); err != nil {
+		return fmt.Errorf("start proxy server: %w", err)
+	}
+	if err := m.proxyAdapter.StartTLSIfNotRunning(443); err != nil {
+		return fmt.Errorf("start TLS proxy server: %w", err)
+	}
+	return nil
+}
+
+func (m *DNSManager) applyRouteProtocol(tunnel DNSTunnel) {
+	protocol, err := proxyadapter.ParseProtocol(tunnel.Protocol)
+	if err != nil || protocol == proxyadapter.ProtocolHTTP1 {
+		return
+	}
+	m.proxyAdapter.SetRouteProtocol(tunnel.DNSURL, protocol)
+}
+
 func (m *DNSManager) getUsedPorts() map[int32]bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -179,36 +627,66 @@ func (m *DNSManager) getUsedPorts() map[int32]bool {
 	usedPorts := make(map[int32]bool, len(m.dnsTunnels))
 	for _, t := range m.dnsTunnels {
 		usedPorts[t.LocalPort] = true
+		for _, b := range t.Backends {
+			usedPorts[b.LocalPort] = true
+		}
 	}
 	return usedPorts
 }
 
 func (m *DNSManager) addTunnels(tunnels []DNSTunnel) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.dnsTunnels = append(m.dnsTunnels, tunnels...)
+	m.mu.Unlock()
+
+	for _, tunnel := range tunnels {
+		m.publishTunnel(TunnelEventReady, tunnel, nil)
+	}
 }
 
 func (m *DNSManager) removeTunnel(dnsURL string) (DNSTunnel, bool) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	var removed DNSTunnel
+	found := false
 	for i, t := range m.dnsTunnels {
 		if t.DNSURL == dnsURL {
 			m.dnsTunnels = append(m.dnsTunnels[:i], m.dnsTunnels[i+1:]...)
-			return t, true
+			removed = t
+			found = true
+			break
 		}
 	}
-	return DNSTunnel{}, false
+	m.mu.Unlock()
+
+	if found {
+		m.publishTunnel(TunnelEventRemoved, removed, nil)
+	}
+	return removed, found
 }
 
 func convertToDNSTunnel(tunnel kube.ServiceTunnel) DNSTunnel {
 	return DNSTunnel{
-		Context:    tunnel.Context,
-		Namespace:  tunnel.Namespace,
-		DNSURL:     tunnel.DNSURL,
-		Pod:        tunnel.Pod,
-		LocalPort:  tunnel.LocalPort,
-		RemotePort: tunnel.RemotePort,
+		Context:     tunnel.Context,
+		Namespace:   tunnel.Namespace,
+		DNSURL:      tunnel.DNSURL,
+		ServiceName: tunnel.ServiceName,
+		ServicePort: tunnel.ServicePort,
+		Kind:        tunnel.Kind,
+		Pod:         tunnel.Pod,
+		LocalPort:   tunnel.LocalPort,
+		RemotePort:  tunnel.RemotePort,
+		Protocol:    normalizeProtocol(tunnel.Protocol),
+		Headless:    tunnel.Headless,
+		State:       TunnelStateActive,
+		Transport:   tunnel.Transport,
+		Backends:    tunnel.Backends,
+	}
+}
+
+func normalizeProtocol(protocol string) string {
+	parsed, err := proxyadapter.ParseProtocol(protocol)
+	if err != nil {
+		return string(proxyadapter.ProtocolHTTP1)
 	}
+	return string(parsed)
 }
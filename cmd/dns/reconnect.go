@@ -0,0 +1,207 @@
+package dns
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+)
+
+const (
+	reconnectAttempts = 5
+	reconnectBackoff  = time.Second
+)
+
+func (m *DNSManager) handleForwardLost(lost kube.LostForward) {
+	select {
+	case <-m.ctx.Done():
+		return
+	default:
+	}
+
+	tunnel, ok := m.findForwardTunnel(lost)
+	if !ok {
+		return
+	}
+
+	switch {
+	case len(tunnel.Backends) > 0:
+		m.loseTunnelBackend(tunnel, lost.Pod)
+	case tunnel.Relay || tunnel.Kind != "":
+		m.failTunnel(tunnel.DNSURL, fmt.Errorf("port forward to pod %s closed", lost.Pod))
+	default:
+		m.reconnectTunnel(tunnel)
+	}
+}
+
+func (m *DNSManager) findForwardTunnel(lost kube.LostForward) (DNSTunnel, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.dnsTunnels {
+		if t.Context != lost.Context {
+			continue
+		}
+		if len(t.Backends) > 0 {
+			if t.Namespace != lost.Namespace {
+				continue
+			}
+			for _, b := range t.Backends {
+				if b.Pod == lost.Pod && b.LocalPort == lost.LocalPort {
+					return t, true
+				}
+			}
+			continue
+		}
+
+		namespace := t.Namespace
+		if t.Relay {
+			namespace = t.RelayNamespace
+		}
+		if namespace == lost.Namespace && t.Pod == lost.Pod && t.LocalPort == lost.LocalPort {
+			return t, true
+		}
+	}
+	return DNSTunnel{}, false
+}
+
+func (m *DNSManager) loseTunnelBackend(tunnel DNSTunnel, pod string) {
+	m.mu.RLock()
+	pool, ok := m.pools[tunnel.DNSURL]
+	m.mu.RUnlock()
+
+	if ok {
+		for _, b := range pool.Backends() {
+			if b.Name == pod {
+				pool.Remove(b)
+			}
+		}
+	} else {
+		m.removeTunnelBackend(tunnel.DNSURL, pod)
+	}
+
+	current, found := m.tunnelsByDNSURL()[tunnel.DNSURL]
+	if !found {
+		return
+	}
+	if len(current.Backends) == 0 {
+		m.reconnectTunnel(current)
+		return
+	}
+
+	updated, _ := m.setTunnelState(tunnel.DNSURL, TunnelStateDegraded)
+	updated.Pod = pod
+	m.publishTunnel(TunnelEventDegraded, updated, nil)
+}
+
+func (m *DNSManager) reconnectTunnel(tunnel DNSTunnel) {
+	updated, changed := m.setTunnelState(tunnel.DNSURL, TunnelStateReconnecting)
+	if !changed {
+		return
+	}
+
+	m.publishTunnel(TunnelEventReconnecting, updated, nil)
+	go m.runReconnect(tunnel)
+}
+
+func (m *DNSManager) runReconnect(tunnel DNSTunnel) {
+	m.stopTunnelForwards(tunnel)
+
+	var err error
+	backoff := reconnectBackoff
+	for attempt := 0; attempt < reconnectAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if current, found := m.tunnelsByDNSURL()[tunnel.DNSURL]; !found || current.State != TunnelStateReconnecting {
+			return
+		}
+		if err = m.restartTunnel(tunnel); err == nil {
+			return
+		}
+	}
+
+	if m.ctx.Err() != nil {
+		return
+	}
+	m.failTunnel(tunnel.DNSURL, err)
+}
+
+func (m *DNSManager) restartTunnel(tunnel DNSTunnel) error {
+	usedPorts := m.getUsedPorts()
+	delete(usedPorts, tunnel.LocalPort)
+
+	var started kube.ServiceTunnel
+	var err error
+	if tunnel.Pinned {
+		started, err = m.kubeAdapter.RegisterServicePodPortForward(m.ctx, tunnel.Context, tunnel.ServiceName, tunnel.Namespace, tunnel.ServicePort, tunnel.Pod, usedPorts)
+	} else {
+		started, err = m.kubeAdapter.RegisterServicePortForward(m.ctx, tunnel.Context, tunnel.ServiceName, tunnel.Namespace, tunnel.ServicePort, usedPorts)
+	}
+	if err != nil {
+		return err
+	}
+
+	startedTunnel := convertToDNSTunnel(started)
+	if err := m.startTunnelPool(startedTunnel); err != nil {
+		m.stopTunnelForwards(startedTunnel)
+		return err
+	}
+
+	m.mu.Lock()
+	index := -1
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL == tunnel.DNSURL && t.State == TunnelStateReconnecting {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		m.mu.Unlock()
+		m.stopTunnelForwards(startedTunnel)
+		return nil
+	}
+	m.dnsTunnels[index].Pod = started.Pod
+	m.dnsTunnels[index].LocalPort = started.LocalPort
+	m.dnsTunnels[index].RemotePort = started.RemotePort
+	m.dnsTunnels[index].Transport = started.Transport
+	m.dnsTunnels[index].Backends = started.Backends
+	m.dnsTunnels[index].State = TunnelStateActive
+	updated := m.dnsTunnels[index]
+	m.mu.Unlock()
+
+	m.proxyAdapter.AddRoute(updated.DNSURL, updated.LocalPort)
+	m.applyRouteProtocol(updated)
+	m.syncVirtualHosts()
+	m.publishTunnel(TunnelEventReady, updated, nil)
+	return nil
+}
+
+func (m *DNSManager) failTunnel(dnsURL string, err error) {
+	if updated, changed := m.setTunnelState(dnsURL, TunnelStateError); changed {
+		m.publishTunnel(TunnelEventError, updated, err)
+	}
+}
+
+func (m *DNSManager) setTunnelState(dnsURL, state string) (DNSTunnel, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, t := range m.dnsTunnels {
+		if t.DNSURL != dnsURL {
+			continue
+		}
+		if t.State == state {
+			return t, false
+		}
+		m.dnsTunnels[i].State = state
+		return m.dnsTunnels[i], true
+	}
+	return DNSTunnel{}, false
+}
@@ -0,0 +1,40 @@
+package dns
+
+import "github.com/byoungmin/kube-service-tunnel/internal/kube"
+
+type RegistrationReport struct {
+	Context string
+	Results []kube.RegistrationResult
+}
+
+func (r RegistrationReport) Registered() []kube.RegistrationResult {
+	var registered []kube.RegistrationResult
+	for _, result := range r.Results {
+		if result.Registered() {
+			registered = append(registered, result)
+		}
+	}
+	return registered
+}
+
+func (r RegistrationReport) Skipped() []kube.RegistrationResult {
+	var skipped []kube.RegistrationResult
+	for _, result := range r.Results {
+		if !result.Registered() {
+			skipped = append(skipped, result)
+		}
+	}
+	return skipped
+}
+
+func (r *RegistrationReport) skipExisting(existing map[string]DNSTunnel) {
+	for i, result := range r.Results {
+		if result.DNSURL == "" {
+			continue
+		}
+		if _, ok := existing[result.DNSURL]; ok {
+			r.Results[i].Reason = kube.SkipReasonAlreadyRegistered
+			r.Results[i].Err = nil
+		}
+	}
+}
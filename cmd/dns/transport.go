@@ -0,0 +1,162 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
+
+func (m *DNSManager) ContextTransport(contextName string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if transport, ok := m.transports[contextName]; ok && transport != "" {
+		return transport
+	}
+	return config.TransportPortForward
+}
+
+func (m *DNSManager) SetContextTransport(contextName, transport string) error {
+	if contextName == "" {
+		return fmt.Errorf("context name is required")
+	}
+	if !slices.Contains(config.Transports, transport) {
+		return fmt.Errorf("unknown transport: %s", transport)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transports[contextName] = transport
+	return nil
+}
+
+func (m *DNSManager) usesAPIServer(contextName string) bool {
+	return m.ContextTransport(contextName) == config.TransportAPIServer
+}
+
+func (m *DNSManager) registerProxyTunnel(ctx context.Context, contextName, serviceName, namespace string, servicePort int32) (DNSTunnel, error) {
+	tunnel, err := m.kubeAdapter.PlanServiceProxy(ctx, contextName, serviceName, namespace, servicePort)
+	if err != nil {
+		return DNSTunnel{}, err
+	}
+	if ctx.Err() != nil {
+		return DNSTunnel{}, ctx.Err()
+	}
+
+	if tunnel.ExternalName != "" {
+		return m.registerExternalTunnel(ctx, tunnel)
+	}
+
+	dnsTunnels, err := m.activateProxyTunnels(contextName, []kube.ServiceTunnel{tunnel})
+	if err != nil {
+		return DNSTunnel{}, err
+	}
+	return dnsTunnels[0], nil
+}
+
+func (m *DNSManager) registerProxyTunnels(ctx context.Context, contextName string, services []kube.Service) (RegistrationReport, error) {
+	tunnels, results, err := m.kubeAdapter.PlanServiceProxies(ctx, contextName, services)
+	if err != nil {
+		return RegistrationReport{Context: contextName}, err
+	}
+	if ctx.Err() != nil {
+		return RegistrationReport{Context: contextName}, ctx.Err()
+	}
+
+	report := RegistrationReport{Context: contextName, Results: results}
+	existing := m.tunnelsByDNSURL()
+	report.skipExisting(existing)
+
+	var fresh []kube.ServiceTunnel
+	for _, tunnel := range tunnels {
+		if _, exists := existing[tunnel.DNSURL]; !exists {
+			fresh = append(fresh, tunnel)
+		}
+	}
+	if len(fresh) == 0 {
+		return report, nil
+	}
+
+	if _, err := m.activateProxyTunnels(contextName, fresh); err != nil {
+		return RegistrationReport{Context: contextName}, err
+	}
+
+	m.syncVirtualHosts()
+	return report, nil
+}
+
+func (m *DNSManager) activateProxyTunnels(contextName string, tunnels []kube.ServiceTunnel) ([]DNSTunnel, error) {
+	transport, err := m.kubeAdapter.ServiceProxyTransport(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsTunnels := make([]DNSTunnel, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		upstream, err := newUpstream(tunnel.ProxyURL, transport)
+		if err != nil {
+			return nil, err
+		}
+
+		dnsTunnel := convertToDNSTunnel(tunnel)
+		dnsTunnel.Transport = config.TransportAPIServer
+		dnsTunnel.upstream = upstream
+		dnsTunnels = append(dnsTunnels, dnsTunnel)
+	}
+
+	if err := m.startProxy(); err != nil {
+		return nil, err
+	}
+
+	for _, dnsTunnel := range dnsTunnels {
+		m.proxyAdapter.AddUpstreamRoute(dnsTunnel.DNSURL, dnsTunnel.upstream)
+	}
+	m.addTunnels(dnsTunnels)
+
+	for i, dnsTunnel := range dnsTunnels {
+		if err := m.hostsFileAdapter.AddEntry(dnsTunnel.DNSURL); err != nil {
+			for _, added := range dnsTunnels[:i] {
+				m.hostsFileAdapter.RemoveEntry(added.DNSURL)
+			}
+			for _, added := range dnsTunnels {
+				m.removeTunnel(added.DNSURL)
+				m.proxyAdapter.RemoveRoute(added.DNSURL)
+			}
+			return nil, fmt.Errorf("add hosts entry: %w", err)
+		}
+	}
+
+	return dnsTunnels, nil
+}
+
+func newUpstream(proxyURL string, transport http.RoundTripper) (*proxyadapter.Upstream, error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse service proxy URL %s: %w", proxyURL, err)
+	}
+	return &proxyadapter.Upstream{URL: parsed, Transport: transport}, nil
+}
+
+func routeBackend(tunnel DNSTunnel, weight int32) (proxyadapter.Backend, bool) {
+	protocol, err := proxyadapter.ParseProtocol(tunnel.Protocol)
+	if err != nil {
+		protocol = proxyadapter.ProtocolHTTP1
+	}
+
+	if tunnel.upstream != nil {
+		return proxyadapter.Backend{Protocol: protocol, Weight: weight, Upstream: tunnel.upstream}, true
+	}
+	if tunnel.Lazy {
+		return proxyadapter.Backend{Protocol: protocol, Weight: weight, Lazy: tunnel.DNSURL}, true
+	}
+	if tunnel.LocalPort == 0 {
+		return proxyadapter.Backend{}, false
+	}
+	return proxyadapter.Backend{LocalPort: tunnel.LocalPort, Protocol: protocol, Weight: weight}, true
+}
@@ -0,0 +1,270 @@
+package dns
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+)
+
+type VirtualHostBackend struct {
+	Tunnel string
+	Weight int32
+}
+
+type VirtualHostPath struct {
+	Prefix   string
+	Exact    bool
+	Headers  []proxyadapter.HeaderMatch
+	Tunnel   string
+	Backends []VirtualHostBackend
+}
+
+func (p VirtualHostPath) match() proxyadapter.PathMatch {
+	return proxyadapter.PathMatch{Path: p.Prefix, Exact: p.Exact, Headers: p.Headers}
+}
+
+func (p VirtualHostPath) backends() []VirtualHostBackend {
+	if len(p.Backends) > 0 {
+		return p.Backends
+	}
+	return []VirtualHostBackend{{Tunnel: p.Tunnel, Weight: 1}}
+}
+
+type VirtualHost struct {
+	Host      string
+	Paths     []VirtualHostPath
+	Source    string
+	Active    bool
+	Capturing bool
+}
+
+type activePath struct {
+	match    proxyadapter.PathMatch
+	backends []proxyadapter.Backend
+}
+
+type virtualHostState struct {
+	host       VirtualHost
+	active     map[string]activePath
+	hostsEntry bool
+}
+
+func newVirtualHostState(vh VirtualHost) (*virtualHostState, error) {
+	if vh.Host == "" {
+		return nil, fmt.Errorf("virtual host name is required")
+	}
+	if len(vh.Paths) == 0 {
+		return nil, fmt.Errorf("virtual host %s needs at least one path", vh.Host)
+	}
+
+	seen := make(map[string]bool, len(vh.Paths))
+	paths := make([]VirtualHostPath, 0, len(vh.Paths))
+	for _, path := range vh.Paths {
+		for _, backend := range path.backends() {
+			if backend.Tunnel == "" {
+				return nil, fmt.Errorf("virtual host %s: tunnel is required for path %s", vh.Host, path.Prefix)
+			}
+		}
+		path.Prefix = proxyadapter.NormalizePath(path.Prefix, path.Exact)
+		key := path.match().Key()
+		if seen[key] {
+			return nil, fmt.Errorf("virtual host %s: duplicate path %s", vh.Host, key)
+		}
+		seen[key] = true
+		paths = append(paths, path)
+	}
+
+	return &virtualHostState{
+		host:   VirtualHost{Host: vh.Host, Paths: paths, Source: vh.Source},
+		active: make(map[string]activePath),
+	}, nil
+}
+
+func (m *DNSManager) GetAllVirtualHosts() []VirtualHost {
+	m.vhMu.Lock()
+	defer m.vhMu.Unlock()
+
+	result := make([]VirtualHost, 0, len(m.virtualHosts))
+	for _, state := range m.virtualHosts {
+		vh := state.host
+		vh.Paths = append([]VirtualHostPath(nil), state.host.Paths...)
+		vh.Active = state.hostsEntry
+		vh.Capturing = m.proxyAdapter.IsCapturing(vh.Host)
+		result = append(result, vh)
+	}
+	return result
+}
+
+func (s *virtualHostState) merge(vh VirtualHost) {
+	seen := make(map[string]bool, len(s.host.Paths))
+	for _, path := range s.host.Paths {
+		seen[path.match().Key()] = true
+	}
+	for _, path := range vh.Paths {
+		key := path.match().Key()
+		if !seen[key] {
+			s.host.Paths = append(s.host.Paths, path)
+			seen[key] = true
+		}
+	}
+
+	if vh.Source != "" && !strings.Contains(s.host.Source, vh.Source) {
+		if s.host.Source == "" {
+			s.host.Source = vh.Source
+		} else {
+			s.host.Source += ", " + vh.Source
+		}
+	}
+}
+
+func (m *DNSManager) RegisterVirtualHost(vh VirtualHost) error {
+	return m.registerVirtualHost(vh, false)
+}
+
+func (m *DNSManager) registerVirtualHost(vh VirtualHost, merge bool) error {
+	state, err := newVirtualHostState(vh)
+	if err != nil {
+		return err
+	}
+
+	tunnels := m.tunnelsByDNSURL()
+	if _, exists := tunnels[state.host.Host]; exists {
+		return fmt.Errorf("virtual host %s conflicts with an existing tunnel", state.host.Host)
+	}
+	for _, path := range state.host.Paths {
+		for _, backend := range path.backends() {
+			if _, exists := tunnels[backend.Tunnel]; !exists {
+				return fmt.Errorf("tunnel not found for DNS URL: %s", backend.Tunnel)
+			}
+		}
+	}
+
+	m.vhMu.Lock()
+	added := true
+	for _, existing := range m.virtualHosts {
+		if existing.host.Host != state.host.Host {
+			continue
+		}
+		if !merge {
+			m.vhMu.Unlock()
+			return fmt.Errorf("virtual host already exists: %s", state.host.Host)
+		}
+		existing.merge(state.host)
+		added = false
+		break
+	}
+	if added {
+		m.virtualHosts = append(m.virtualHosts, state)
+	}
+	m.vhMu.Unlock()
+
+	if err := m.syncVirtualHosts(); err != nil {
+		if added {
+			m.UnregisterVirtualHost(state.host.Host)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *DNSManager) UnregisterVirtualHost(host string) error {
+	m.vhMu.Lock()
+	defer m.vhMu.Unlock()
+
+	for i, state := range m.virtualHosts {
+		if state.host.Host != host {
+			continue
+		}
+
+		if state.hostsEntry {
+			if err := m.hostsFileAdapter.RemoveEntry(host); err != nil {
+				return fmt.Errorf("remove hosts entry: %w", err)
+			}
+		}
+		m.proxyAdapter.RemoveRoute(host)
+		m.virtualHosts = append(m.virtualHosts[:i], m.virtualHosts[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("virtual host not found: %s", host)
+}
+
+func (m *DNSManager) syncVirtualHosts() error {
+	m.vhMu.Lock()
+	defer m.vhMu.Unlock()
+
+	tunnels := m.tunnelsByDNSURL()
+
+	for _, state := range m.virtualHosts {
+		desired := make(map[string]activePath, len(state.host.Paths))
+		for _, path := range state.host.Paths {
+			var backends []proxyadapter.Backend
+			for _, backend := range path.backends() {
+				tunnel, ok := tunnels[backend.Tunnel]
+				if !ok {
+					continue
+				}
+				if routed, ok := routeBackend(tunnel, backend.Weight); ok {
+					backends = append(backends, routed)
+				}
+			}
+			if len(backends) == 0 {
+				continue
+			}
+			match := path.match()
+			desired[match.Key()] = activePath{match: match, backends: backends}
+		}
+
+		for key, current := range state.active {
+			if target, ok := desired[key]; !ok || !slices.Equal(target.backends, current.backends) {
+				m.proxyAdapter.RemoveMatchRoute(state.host.Host, current.match)
+				delete(state.active, key)
+			}
+		}
+		for key, target := range desired {
+			if _, ok := state.active[key]; ok {
+				continue
+			}
+			if err := m.proxyAdapter.AddMatchRoute(state.host.Host, target.match, target.backends); err != nil {
+				return fmt.Errorf("add route for %s: %w", state.host.Host, err)
+			}
+			state.active[key] = target
+		}
+
+		if len(desired) > 0 && !state.hostsEntry {
+			if err := m.hostsFileAdapter.AddEntry(state.host.Host); err != nil {
+				return fmt.Errorf("add hosts entry: %w", err)
+			}
+			state.hostsEntry = true
+		} else if len(desired) == 0 && state.hostsEntry {
+			if err := m.hostsFileAdapter.RemoveEntry(state.host.Host); err != nil {
+				return fmt.Errorf("remove hosts entry: %w", err)
+			}
+			state.hostsEntry = false
+		}
+	}
+
+	return nil
+}
+
+func (m *DNSManager) resetVirtualHosts() {
+	m.vhMu.Lock()
+	defer m.vhMu.Unlock()
+
+	for _, state := range m.virtualHosts {
+		state.active = make(map[string]activePath)
+		state.hostsEntry = false
+	}
+}
+
+func (m *DNSManager) tunnelsByDNSURL() map[string]DNSTunnel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]DNSTunnel, len(m.dnsTunnels))
+	for _, t := range m.dnsTunnels {
+		result[t.DNSURL] = t
+	}
+	return result
+}
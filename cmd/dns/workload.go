@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+)
+
+func (m *DNSManager) RegisterWorkloadTunnel(ctx context.Context, contextName, namespace, kind, name string) ([]string, error) {
+	if contextName == "" || namespace == "" || kind == "" || name == "" {
+		return nil, fmt.Errorf("context name, namespace, workload kind and name are required")
+	}
+
+	if m.usesAPIServer(contextName) {
+		return nil, fmt.Errorf("workload and pod tunnels require the %s transport", config.TransportPortForward)
+	}
+
+	event := TunnelEvent{Type: TunnelEventRegistering, Context: contextName, Namespace: namespace, Name: name}
+	m.publish(event)
+
+	tunnels, err := m.kubeAdapter.RegisterWorkloadPortForwards(ctx, contextName, namespace, kind, name, m.getUsedPorts())
+	if err != nil {
+		m.publishFailure(event, err)
+		return nil, err
+	}
+
+	dnsURLs, err := m.registerPodTunnels(ctx, tunnels)
+	m.publishFailure(event, err)
+	return dnsURLs, err
+}
+
+func (m *DNSManager) RegisterPodTunnel(ctx context.Context, contextName, namespace, pod string) ([]string, error) {
+	if contextName == "" || namespace == "" || pod == "" {
+		return nil, fmt.Errorf("context name, namespace and pod name are required")
+	}
+
+	if m.usesAPIServer(contextName) {
+		return nil, fmt.Errorf("workload and pod tunnels require the %s transport", config.TransportPortForward)
+	}
+
+	event := TunnelEvent{Type: TunnelEventRegistering, Context: contextName, Namespace: namespace, Name: pod, Pod: pod}
+	m.publish(event)
+
+	tunnels, err := m.kubeAdapter.RegisterPodPortForwards(ctx, contextName, namespace, pod, m.getUsedPorts())
+	if err != nil {
+		m.publishFailure(event, err)
+		return nil, err
+	}
+
+	dnsURLs, err := m.registerPodTunnels(ctx, tunnels)
+	m.publishFailure(event, err)
+	return dnsURLs, err
+}
+
+func (m *DNSManager) registerPodTunnels(ctx context.Context, tunnels []kube.ServiceTunnel) ([]string, error) {
+	if ctx.Err() != nil {
+		for _, tunnel := range tunnels {
+			m.stopTunnelForwards(convertToDNSTunnel(tunnel))
+		}
+		return nil, ctx.Err()
+	}
+
+	existing := m.tunnelsByDNSURL()
+
+	var fresh []kube.ServiceTunnel
+	var dnsURLs []string
+	for _, tunnel := range tunnels {
+		if _, exists := existing[tunnel.DNSURL]; exists {
+			m.kubeAdapter.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
+			continue
+		}
+		fresh = append(fresh, tunnel)
+		dnsURLs = append(dnsURLs, tunnel.DNSURL)
+	}
+
+	if len(fresh) == 0 {
+		return nil, fmt.Errorf("all ports are already tunneled")
+	}
+
+	if err := m.activateTunnels(fresh); err != nil {
+		return nil, err
+	}
+
+	m.syncVirtualHosts()
+	return dnsURLs, nil
+}
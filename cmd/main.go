@@ -4,8 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui"
+	"github.com/byoungmin/kube-service-tunnel/internal/cert"
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
 )
 
 func checkHostsFilePermission() error {
@@ -18,18 +24,205 @@ func checkHostsFilePermission() error {
 	return nil
 }
 
+type listFlag struct {
+	values *[]string
+}
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*f.values = values
+	return nil
+}
+
+type transportFlag struct {
+	contexts  *map[string]config.ContextConfig
+	transport string
+}
+
+func (f transportFlag) String() string {
+	if f.contexts == nil {
+		return ""
+	}
+	var names []string
+	for name, context := range *f.contexts {
+		if context.Transport == f.transport {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (f transportFlag) Set(value string) error {
+	if *f.contexts == nil {
+		*f.contexts = make(map[string]config.ContextConfig)
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			context := (*f.contexts)[name]
+			context.Transport = f.transport
+			(*f.contexts)[name] = context
+		}
+	}
+	return nil
+}
+
+type portRangeFlag struct {
+	ports *config.PortsConfig
+}
+
+func (f portRangeFlag) String() string {
+	if f.ports == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", f.ports.Min, f.ports.Max)
+}
+
+func (f portRangeFlag) Set(value string) error {
+	lower, upper, ok := strings.Cut(value, "-")
+	if !ok {
+		return fmt.Errorf("port range must look like 40000-49999")
+	}
+	minPort, err := strconv.ParseInt(strings.TrimSpace(lower), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid lower port: %w", err)
+	}
+	maxPort, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid upper port: %w", err)
+	}
+	if minPort < 1 || maxPort > 65535 || minPort > maxPort {
+		return fmt.Errorf("port range %d-%d must lie within 1-65535", minPort, maxPort)
+	}
+	f.ports.Min = int32(minPort)
+	f.ports.Max = int32(maxPort)
+	return nil
+}
+
+func runCACommand(args []string) error {
+	var outPath string
+
+	flags := flag.NewFlagSet("ca", flag.ExitOnError)
+	flags.StringVar(&outPath, "out", "", "Write the CA certificate to this file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kube-service-tunnel ca [--out file]\n\nPrints the local CA certificate used for HTTPS tunnels.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	configDir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+
+	authority, err := cert.NewAuthority(configDir)
+	if err != nil {
+		return fmt.Errorf("load certificate authority: %w", err)
+	}
+
+	if outPath == "" {
+		_, err := os.Stdout.Write(authority.CertificatePEM())
+		return err
+	}
+
+	if err := os.WriteFile(outPath, authority.CertificatePEM(), 0644); err != nil {
+		return fmt.Errorf("write CA certificate: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "CA certificate written to %s (stored at %s)\n", outPath, authority.CertificatePath())
+	return nil
+}
+
+func loadConfig(configPath string, cfg *config.Config) error {
+	required := configPath != ""
+	if !required {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		configPath = defaultPath
+	}
+
+	setFlags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = f.Value.String()
+	})
+
+	loaded, err := config.Load(configPath, required)
+	if err != nil {
+		return err
+	}
+	*cfg = *loaded
+
+	for name, value := range setFlags {
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("apply flag --%s: %w", name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
 func main() {
 	var kubeconfigPath string
+	var configPath string
+	cfg := config.Default()
+
+	if len(os.Args) > 1 && os.Args[1] == "ca" {
+		if err := runCACommand(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	flag.StringVar(&kubeconfigPath, "kubeconfig", "", "Path to kubeconfig file (default: ~/.kube/config)")
+	flag.StringVar(&configPath, "config", "", "Path to config file (default: <config dir>/config.yaml)")
+	flag.IntVar(&cfg.Inspector.Capacity, "inspect-capacity", cfg.Inspector.Capacity, "Number of recent requests kept per tunnel for the request inspector")
+	flag.BoolVar(&cfg.Inspector.CaptureHeaders, "inspect-headers", cfg.Inspector.CaptureHeaders, "Record request and response headers in the request inspector")
+	flag.Int64Var(&cfg.Inspector.MaxBodyBytes, "inspect-body-limit", cfg.Inspector.MaxBodyBytes, "Record up to this many bytes of request and response bodies (0 disables)")
+	flag.StringVar(&cfg.HAR.Dir, "har-dir", cfg.HAR.Dir, "Directory for HAR capture files (default: <config dir>/har)")
+	flag.Var(listFlag{&cfg.HAR.CaptureHosts}, "har-capture", "Comma-separated tunnel hosts or glob patterns to capture to HAR automatically (e.g. api.default,*.staging)")
+	flag.Int64Var(&cfg.HAR.MaxBodyBytes, "har-body-limit", cfg.HAR.MaxBodyBytes, "Maximum request and response body bytes written per HAR entry")
+	flag.IntVar(&cfg.HAR.MaxEntries, "har-max-entries", cfg.HAR.MaxEntries, "Maximum entries written per HAR capture file")
+	flag.Var(listFlag{&cfg.HAR.RedactHeaders}, "har-redact", "Comma-separated headers whose values are redacted in HAR files")
+	flag.BoolVar(&cfg.Lazy.Enabled, "lazy", cfg.Lazy.Enabled, "Open port forwards for registered contexts on the first request instead of up front")
+	flag.DurationVar((*time.Duration)(&cfg.Lazy.IdleTimeout), "lazy-idle-timeout", time.Duration(cfg.Lazy.IdleTimeout), "Close lazy port forwards after this long without requests (0 keeps them open)")
+	flag.IntVar(&cfg.LoadBalancing.Replicas, "lb-replicas", cfg.LoadBalancing.Replicas, "Number of ready pods each tunnel forwards to and balances across")
+	flag.StringVar(&cfg.LoadBalancing.Policy, "lb-policy", cfg.LoadBalancing.Policy, "Load balancing policy across pods: round-robin or least-connections")
+	flag.Var(listFlag{&cfg.Services.ExcludeTypes}, "exclude-service-types", "Comma-separated service types to hide and skip when tunneling (e.g. NodePort,LoadBalancer)")
+	flag.BoolVar(&cfg.Relay.Enabled, "relay", cfg.Relay.Enabled, "Reach ExternalName targets that are only routable from the cluster through a relay pod")
+	flag.StringVar(&cfg.Relay.Image, "relay-image", cfg.Relay.Image, "Container image for relay pods; must provide socat as its entrypoint")
+	flag.StringVar(&cfg.Relay.Namespace, "relay-namespace", cfg.Relay.Namespace, "Namespace for relay pods (default: the service's namespace)")
+	flag.Var(transportFlag{&cfg.Contexts, config.TransportAPIServer}, "api-server-contexts", "Comma-separated contexts whose HTTP services are reached through the API server's services/proxy endpoint instead of port-forward")
+	flag.Var(portRangeFlag{&cfg.Ports}, "port-range", "Range of local ports assigned to tunnels, as min-max")
 	flag.Parse()
 
+	if err := loadConfig(configPath, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	if err := checkHostsFilePermission(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := tui.Run(kubeconfigPath); err != nil {
+	if err := tui.Run(kubeconfigPath, cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
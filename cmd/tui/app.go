@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
 	"github.com/byoungmin/kube-service-tunnel/internal/kube"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -36,11 +38,19 @@ type App struct {
 	manager     dns.DNSManagerInterface
 	kubeAdapter kube.KubeAdapterInterface
 
+	serviceChoices map[string]serviceChoice
+	choiceMu       sync.Mutex
+
+	operationCancel context.CancelFunc
+	operationMu     sync.Mutex
+
+	inspector *inspectorView
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-func Run(kubeconfigPath string) error {
+func Run(kubeconfigPath string, cfg *config.Config) error {
 	backgroundColor = tcell.NewRGBColor(0, 0, 0)
 	textColor = tcell.ColorWhite
 
@@ -50,26 +60,22 @@ func Run(kubeconfigPath string) error {
 	tview.Styles.PrimaryTextColor = textColor
 	tview.Styles.SecondaryTextColor = textColor
 
-	manager, err := dns.NewDNSManager(kubeconfigPath)
+	manager, err := dns.NewDNSManager(kubeconfigPath, cfg)
 	if err != nil {
 		return fmt.Errorf("create service tunnel manager: %w", err)
 	}
 
-	kubeAdapter, err := kube.NewKubeAdapter(kubeconfigPath)
-	if err != nil {
-		return fmt.Errorf("create kube adapter: %w", err)
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	app := &App{
-		app:         tview.NewApplication(),
-		manager:     manager,
-		kubeAdapter: kubeAdapter,
-		ctx:         ctx,
-		cancel:      cancel,
-		store:       store.NewStore(),
+		app:            tview.NewApplication(),
+		manager:        manager,
+		kubeAdapter:    manager.KubeAdapter(),
+		serviceChoices: make(map[string]serviceChoice),
+		ctx:            ctx,
+		cancel:         cancel,
+		store:          store.NewStore(),
 	}
 
 	app.setupUI()
@@ -89,6 +95,8 @@ func Run(kubeconfigPath string) error {
 		time.Sleep(100 * time.Millisecond)
 		app.fetchAllResources()
 	}()
+	go app.watchTunnelEvents()
+	go app.watchVirtualHostRows()
 
 	return app.app.Run()
 }
@@ -126,6 +134,7 @@ func (app *App) setupUI() {
 	app.UpdateContextList()
 	app.SetupLoadingSubscription()
 	app.SetupFocusSubscription()
+	app.SetupTunnelSubscription()
 }
 
 func (app *App) SetupFocusSubscription() {
@@ -190,6 +199,9 @@ func (app *App) fetchAllResources() {
 func (app *App) handleGlobalInput(event *tcell.EventKey) *tcell.EventKey {
 	state := app.store.GetState()
 	if state.IsLoading {
+		if event.Key() == tcell.KeyEscape {
+			app.cancelOperation()
+		}
 		return nil
 	}
 	if event.Key() == tcell.KeyCtrlC {
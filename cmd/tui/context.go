@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
 	"github.com/byoungmin/kube-service-tunnel/internal/kube"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -77,10 +78,36 @@ func (a *App) handleContextInput(event *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyCtrlP:
 		a.handleRegisterAllServices()
 		return nil
+	case tcell.KeyRune:
+		if event.Rune() == 'a' {
+			a.toggleContextTransport()
+			return nil
+		}
 	}
 	return event
 }
 
+func (a *App) toggleContextTransport() {
+	row, _ := a.contextList.GetSelection()
+	contexts := a.GetContexts()
+	if row < 0 || row >= len(contexts) {
+		return
+	}
+	contextName := contexts[row].Name
+
+	transport := config.TransportAPIServer
+	if a.manager.ContextTransport(contextName) == config.TransportAPIServer {
+		transport = config.TransportPortForward
+	}
+	if err := a.manager.SetContextTransport(contextName, transport); err != nil {
+		a.SetMessage(fmt.Sprintf("Failed to change transport: %v", err))
+		return
+	}
+
+	a.UpdateContextList()
+	a.SetMessage(fmt.Sprintf("Context %s now uses the %s transport for new tunnels", contextName, transport))
+}
+
 func (a *App) handleRegisterAllServices() {
 	go func() {
 		state := a.store.GetState()
@@ -99,17 +126,18 @@ func (a *App) handleRegisterAllServices() {
 			allServices = append(allServices, services...)
 		}
 
-		a.store.SetLoading(true)
-		defer a.store.SetLoading(false)
-
-		if err := a.manager.RegisterAllByContext(contextName, allServices); err != nil {
-			a.store.SetMessage(fmt.Sprintf("Failed to register all services: %v", err))
-		} else {
-			a.app.QueueUpdateDraw(func() {
-				a.UpdateDNSView()
-			})
-			a.store.SetMessage(fmt.Sprintf("All services registered for context: %s", contextName))
+		ctx, done := a.startOperation()
+		report, err := a.manager.RegisterAllByContext(ctx, contextName, allServices)
+		done()
+		if err != nil {
+			a.store.SetMessage(operationMessage("Failed to register all services: %v", err))
+			return
 		}
+
+		a.app.QueueUpdateDraw(func() {
+			a.showRegistrationReport(report)
+		})
+		a.store.SetMessage(fmt.Sprintf("Registered %d services for context %s, skipped %d", len(report.Registered()), contextName, len(report.Skipped())))
 	}()
 }
 
@@ -34,13 +34,13 @@ func UpdateHelpText(helpView *tview.TextView, focusType string) {
 
 	switch focusType {
 	case "context":
-		baseText = "Tab: Next (Namespaces)\nEnter: Select context\nCtrl+P: Register all services\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
+		baseText = "Tab: Next (Namespaces)\nEnter: Select context\nCtrl+P: Register all services\na: Toggle API server transport\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
 	case "namespace":
 		baseText = "Tab: Next (Services)\nShift+Tab: Previous (Context)\nEnter: Select namespace\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
 	case "services":
-		baseText = "Tab: Next (Tunnel)\nShift+Tab: Previous (Namespaces)\nEnter: Register & port forward service\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
+		baseText = "Tab: Next (Tunnel)\nShift+Tab: Previous (Namespaces)\nEnter: Register & port forward selected resource\nd: Choose pod and port for service\ns: Show services\ni: Show ingresses\nr: Show HTTPRoutes\nw: Show workloads\np: Show pods\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
 	case "tunnel":
-		baseText = "Tab: Next (Context)\nShift+Tab: Previous (Services)\nDelete: Delete port forward\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
+		baseText = "Tab: Next (Context)\nShift+Tab: Previous (Services)\nDelete: Delete port forward\np: Cycle protocol (http1/h2c/grpc)\ni: Inspect requests\nh: Toggle HAR capture\nv: Add virtual host\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
 	default:
 		baseText = "Tab: Navigate\nEnter: Select\nCtrl+B: Change background color\nCtrl+T: Change text color\nCtrl+C: Exit"
 	}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+)
+
+func (a *App) loadHTTPRoutes(contextName, namespace string) {
+	if contextName == "" || namespace == "" {
+		return
+	}
+
+	routes, err := a.kubeAdapter.ListHTTPRoutes(a.ctx, namespace, contextName)
+	if err != nil {
+		a.store.SetMessage(fmt.Sprintf("Error fetching HTTPRoutes: %v", err))
+		return
+	}
+
+	state := a.store.GetState()
+	if state.SelectedContext != contextName || state.SelectedNamespace != namespace {
+		return
+	}
+	a.store.SetHTTPRoutes(routes)
+	if len(routes) == 0 {
+		a.store.SetMessage(fmt.Sprintf("No HTTPRoutes found in namespace %s", namespace))
+	}
+}
+
+func (a *App) handleHTTPRouteSelection() {
+	routes := a.GetHTTPRoutes()
+	if len(routes) == 0 {
+		a.store.SetMessage("No HTTPRoutes found in selected namespace")
+		return
+	}
+
+	selectedRow, _ := a.mainView.GetSelection()
+	index := selectedRow - 1
+	if index < 0 || index >= len(routes) {
+		a.store.SetMessage("Please select an HTTPRoute (use arrow keys to navigate, then press Enter)")
+		return
+	}
+
+	route := routes[index]
+	contextName := a.GetSelectedContext()
+
+	go func() {
+		ctx, done := a.startOperation()
+		defer func() {
+			done()
+			go a.store.SetFocus(store.FocusServices)
+		}()
+
+		skipped, err := a.manager.RegisterHTTPRoute(ctx, contextName, route.Namespace, route.Name)
+		if err != nil {
+			a.store.SetMessage(operationMessage("HTTPRoute tunnel failed: %v", err))
+			return
+		}
+
+		a.app.QueueUpdateDraw(func() {
+			a.UpdateDNSView()
+		})
+		message := fmt.Sprintf("HTTPRoute tunneled: %s (%s)", route.Name, strings.Join(route.Hostnames, ", "))
+		if len(skipped) > 0 {
+			message += fmt.Sprintf("; skipped %s", strings.Join(skipped, "; "))
+		}
+		a.store.SetMessage(message)
+	}()
+}
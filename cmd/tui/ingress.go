@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+)
+
+func (a *App) loadIngresses(contextName, namespace string) {
+	if contextName == "" || namespace == "" {
+		return
+	}
+
+	ingresses, err := a.kubeAdapter.ListIngresses(a.ctx, namespace, contextName)
+	if err != nil {
+		a.store.SetMessage(fmt.Sprintf("Error fetching ingresses: %v", err))
+		return
+	}
+
+	state := a.store.GetState()
+	if state.SelectedContext != contextName || state.SelectedNamespace != namespace {
+		return
+	}
+	a.store.SetIngresses(ingresses)
+	if len(ingresses) == 0 {
+		a.store.SetMessage(fmt.Sprintf("No ingresses found in namespace %s", namespace))
+	}
+}
+
+func (a *App) handleIngressSelection() {
+	ingresses := a.GetIngresses()
+	if len(ingresses) == 0 {
+		a.store.SetMessage("No ingresses found in selected namespace")
+		return
+	}
+
+	selectedRow, _ := a.mainView.GetSelection()
+	index := selectedRow - 1
+	if index < 0 || index >= len(ingresses) {
+		a.store.SetMessage("Please select an ingress (use arrow keys to navigate, then press Enter)")
+		return
+	}
+
+	ing := ingresses[index]
+	contextName := a.GetSelectedContext()
+
+	go func() {
+		ctx, done := a.startOperation()
+		defer func() {
+			done()
+			go a.store.SetFocus(store.FocusServices)
+		}()
+
+		skipped, err := a.manager.RegisterIngress(ctx, contextName, ing.Namespace, ing.Name)
+		if err != nil {
+			a.store.SetMessage(operationMessage("Ingress tunnel failed: %v", err))
+			return
+		}
+
+		a.app.QueueUpdateDraw(func() {
+			a.UpdateDNSView()
+		})
+		message := fmt.Sprintf("Ingress tunneled: %s (%s)", ing.Name, strings.Join(ing.Hosts(), ", "))
+		if len(skipped) > 0 {
+			message += fmt.Sprintf("; skipped %s", strings.Join(skipped, "; "))
+		}
+		a.store.SetMessage(message)
+	}()
+}
@@ -0,0 +1,296 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+type inspectorView struct {
+	host      string
+	table     *tview.Table
+	details   *tview.TextView
+	exchanges []proxyadapter.Exchange
+	detailsID uint64
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func (a *App) handleTunnelInspect() {
+	row, ok := a.getSelectedTunnelRow()
+	if !ok {
+		return
+	}
+	a.showInspector(row.host())
+}
+
+func (a *App) showInspector(host string) {
+	if a.pages.HasPage("inspector") {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	view := &inspectorView{
+		host:    host,
+		table:   tview.NewTable(),
+		details: tview.NewTextView(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	view.table.SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetTitle(fmt.Sprintf(" Requests: %s ", host)).
+		SetBorder(true)
+	a.ApplyViewStyles(view.table)
+	view.table.SetBorderColor(focusedBorderColor)
+
+	view.details.SetDynamicColors(true).
+		SetWordWrap(true).
+		SetScrollable(true).
+		SetTitle(" Details ").
+		SetBorder(true)
+	a.ApplyViewStyles(view.details)
+
+	view.table.SetSelectionChangedFunc(func(row, column int) {
+		a.updateInspectorDetails(view, row)
+	})
+	view.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			a.hideInspector()
+			return nil
+		case tcell.KeyTab:
+			a.app.SetFocus(view.details)
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'q':
+				a.hideInspector()
+				return nil
+			case 'r':
+				a.replaySelectedExchange(view)
+				return nil
+			}
+		}
+		return event
+	})
+	view.details.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyTab, tcell.KeyBacktab:
+			a.app.SetFocus(view.table)
+			return nil
+		}
+		return event
+	})
+
+	help := tview.NewTextView().
+		SetText("Enter/↑↓: Select request  r: Replay  Tab: Scroll details  Esc: Back")
+	a.ApplyViewStyles(help)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view.table, 0, 1, true).
+		AddItem(view.details, 0, 1, false).
+		AddItem(help, 1, 0, false)
+	a.ApplyViewStyles(layout)
+
+	a.refreshInspector(view)
+	a.inspector = view
+	a.pages.AddPage("inspector", layout, true, true)
+	a.app.SetFocus(view.table)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-view.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			a.app.QueueUpdateDraw(func() {
+				a.refreshOpenInspector(view)
+			})
+		}
+	}()
+}
+
+func (a *App) hideInspector() {
+	if !a.pages.HasPage("inspector") {
+		return
+	}
+	if a.inspector != nil {
+		a.inspector.cancel()
+		a.inspector = nil
+	}
+	a.pages.RemovePage("inspector")
+	a.app.SetFocus(a.dnsView)
+}
+
+func (a *App) refreshOpenInspector(view *inspectorView) {
+	if view.ctx.Err() != nil || a.inspector != view {
+		return
+	}
+	a.refreshInspector(view)
+}
+
+func (a *App) refreshInspector(view *inspectorView) {
+	var selectedID uint64
+	if row, _ := view.table.GetSelection(); row > 0 && row <= len(view.exchanges) {
+		selectedID = view.exchanges[row-1].ID
+	}
+
+	view.exchanges = a.manager.GetTunnelRequests(view.host)
+	view.table.Clear()
+
+	headerCell := func(text string, expansion int) *tview.TableCell {
+		return newTableCell(text).
+			SetSelectable(false).
+			SetExpansion(expansion)
+	}
+	dataCell := func(text string, expansion int) *tview.TableCell {
+		return newTableCell(text).SetExpansion(expansion)
+	}
+
+	view.table.SetCell(0, 0, headerCell("Time", 1))
+	view.table.SetCell(0, 1, headerCell("Method", 1))
+	view.table.SetCell(0, 2, headerCell("Path", 4))
+	view.table.SetCell(0, 3, headerCell("Status", 1))
+	view.table.SetCell(0, 4, headerCell("Latency", 1))
+	view.table.SetCell(0, 5, headerCell("Req", 1))
+	view.table.SetCell(0, 6, headerCell("Resp", 1))
+
+	if len(view.exchanges) == 0 {
+		view.table.SetCell(1, 0, newTableCell("No requests recorded yet").SetExpansion(1))
+		view.details.SetText("")
+		view.detailsID = 0
+		return
+	}
+
+	selectedRow := 1
+	for i, exchange := range view.exchanges {
+		row := i + 1
+		view.table.SetCell(row, 0, dataCell(exchange.StartedAt.Format("15:04:05"), 1))
+		view.table.SetCell(row, 1, dataCell(exchange.Method, 1))
+		view.table.SetCell(row, 2, dataCell(exchange.URL, 4))
+		view.table.SetCell(row, 3, dataCell(fmt.Sprintf("%d", exchange.Status), 1))
+		view.table.SetCell(row, 4, dataCell(formatLatency(exchange.Latency), 1))
+		view.table.SetCell(row, 5, dataCell(formatBytes(exchange.RequestSize), 1))
+		view.table.SetCell(row, 6, dataCell(formatBytes(exchange.ResponseSize), 1))
+		if exchange.ID == selectedID {
+			selectedRow = row
+		}
+	}
+
+	view.table.Select(selectedRow, 0)
+	a.updateInspectorDetails(view, selectedRow)
+}
+
+func (a *App) updateInspectorDetails(view *inspectorView, row int) {
+	if row <= 0 || row > len(view.exchanges) {
+		view.details.SetText("")
+		view.detailsID = 0
+		return
+	}
+
+	exchange := view.exchanges[row-1]
+	if exchange.ID == view.detailsID {
+		return
+	}
+	view.detailsID = exchange.ID
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", exchange.Method, tview.Escape(exchange.URL), exchange.Proto)
+	fmt.Fprintf(&b, "Status: %d %s\n", exchange.Status, http.StatusText(exchange.Status))
+	fmt.Fprintf(&b, "Started: %s  Latency: %s\n", exchange.StartedAt.Format("2006-01-02 15:04:05.000"), formatLatency(exchange.Latency))
+	fmt.Fprintf(&b, "Request: %s  Response: %s\n", formatBytes(exchange.RequestSize), formatBytes(exchange.ResponseSize))
+
+	writeHeaders(&b, "Request Headers", exchange.RequestHeaders)
+	writeBody(&b, "Request Body", exchange.RequestBody, exchange.RequestTruncated)
+	writeHeaders(&b, "Response Headers", exchange.ResponseHeaders)
+	writeBody(&b, "Response Body", exchange.ResponseBody, exchange.ResponseTruncated)
+
+	view.details.SetText(b.String())
+	view.details.ScrollToBeginning()
+}
+
+func (a *App) replaySelectedExchange(view *inspectorView) {
+	row, _ := view.table.GetSelection()
+	if row <= 0 || row > len(view.exchanges) {
+		a.SetMessage("Please select a request to replay")
+		return
+	}
+
+	exchange := view.exchanges[row-1]
+	go func() {
+		status, err := a.manager.ReplayTunnelRequest(view.host, exchange.ID)
+		if err != nil {
+			a.store.SetMessage(fmt.Sprintf("Replay failed: %v", err))
+			return
+		}
+		a.store.SetMessage(fmt.Sprintf("Replayed %s %s: %d %s", exchange.Method, exchange.URL, status, http.StatusText(status)))
+		a.app.QueueUpdateDraw(func() {
+			a.refreshOpenInspector(view)
+		})
+	}()
+}
+
+func writeHeaders(b *strings.Builder, title string, headers http.Header) {
+	if len(headers) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "\n%s\n", title)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(b, "  %s: %s\n", name, tview.Escape(value))
+		}
+	}
+}
+
+func writeBody(b *strings.Builder, title string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n%s", title)
+	if truncated {
+		b.WriteString(" (truncated)")
+	}
+	fmt.Fprintf(b, "\n%s\n", tview.Escape(string(body)))
+}
+
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fK", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/(1024*1024))
+	}
+}
@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,6 +11,8 @@ import (
 	"github.com/rivo/tview"
 )
 
+const operationCancelledMessage = "Operation cancelled"
+
 var loadingFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
 func (a *App) SetupLoadingSubscription() {
@@ -68,7 +72,11 @@ func (a *App) showLoadingModal() {
 			}
 			a.app.QueueUpdateDraw(func() {
 				colorHex := colorToHex(systemColor)
-				content.SetText(fmt.Sprintf("\n[%s]%s[%s] Loading...[-]", colorHex, loadingFrames[i%len(loadingFrames)], colorHex))
+				text := fmt.Sprintf("\n[%s]%s[%s] Loading...[-]", colorHex, loadingFrames[i%len(loadingFrames)], colorHex)
+				if a.hasOperation() {
+					text += "\n[::d]Esc to cancel[-:-:-]"
+				}
+				content.SetText(text)
 			})
 			i++
 			time.Sleep(80 * time.Millisecond)
@@ -85,3 +93,44 @@ func (a *App) hideLoadingModal() {
 		a.app.SetFocus(target)
 	}
 }
+
+func (a *App) startOperation() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(a.ctx)
+
+	a.operationMu.Lock()
+	a.operationCancel = cancel
+	a.operationMu.Unlock()
+	a.store.SetLoading(true)
+
+	return ctx, func() {
+		a.operationMu.Lock()
+		a.operationCancel = nil
+		a.operationMu.Unlock()
+		cancel()
+		a.store.SetLoading(false)
+	}
+}
+
+func (a *App) hasOperation() bool {
+	a.operationMu.Lock()
+	defer a.operationMu.Unlock()
+	return a.operationCancel != nil
+}
+
+func (a *App) cancelOperation() {
+	a.operationMu.Lock()
+	cancel := a.operationCancel
+	a.operationMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		a.store.SetMessage("Cancelling...")
+	}
+}
+
+func operationMessage(format string, err error) string {
+	if errors.Is(err, context.Canceled) {
+		return operationCancelledMessage
+	}
+	return fmt.Sprintf(format, err)
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func (a *App) showRegistrationReport(report dns.RegistrationReport) {
+	if a.pages.HasPage("report") {
+		a.pages.RemovePage("report")
+	}
+
+	content := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetText(formatRegistrationReport(report))
+	content.SetBackgroundColor(backgroundColor)
+
+	closeButton := tview.NewButton("Close").SetSelectedFunc(a.closeRegistrationReport)
+
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(content, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(closeButton, 9, 0, true).
+			AddItem(nil, 0, 1, false), 1, 0, true)
+	contentFlex.SetBorder(true).SetTitle(fmt.Sprintf(" Registration: %s ", report.Context))
+	contentFlex.SetBackgroundColor(backgroundColor)
+	contentFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			a.closeRegistrationReport()
+			return nil
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+			content.InputHandler()(event, nil)
+			return nil
+		}
+		return event
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(contentFlex, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+
+	a.pages.AddPage("report", modal, true, true)
+	a.app.SetFocus(closeButton)
+}
+
+func (a *App) closeRegistrationReport() {
+	a.pages.RemovePage("report")
+
+	state := a.store.GetState()
+	a.app.SetFocus(a.getWidgetForFocus(state.Focus))
+}
+
+func formatRegistrationReport(report dns.RegistrationReport) string {
+	registered := report.Registered()
+	skipped := report.Skipped()
+
+	colorHex := colorToHex(systemColor)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Registered: %d   Skipped: %d\n", len(registered), len(skipped))
+
+	byReason := make(map[string][]kube.RegistrationResult)
+	for _, result := range skipped {
+		byReason[result.Reason] = append(byReason[result.Reason], result)
+	}
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "\n[%s]Skipped, %s (%d)[-]\n", colorHex, reason, len(byReason[reason]))
+		for _, result := range byReason[reason] {
+			fmt.Fprintf(&b, "  %s", formatResultName(result))
+			if result.Err != nil {
+				fmt.Fprintf(&b, ": %s", tview.Escape(result.Err.Error()))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(registered) > 0 {
+		fmt.Fprintf(&b, "\n[%s]Registered (%d)[-]\n", colorHex, len(registered))
+		for _, result := range registered {
+			fmt.Fprintf(&b, "  %s\n", result.DNSURL)
+		}
+	}
+	return b.String()
+}
+
+func formatResultName(result kube.RegistrationResult) string {
+	if result.Service == "" {
+		return result.Namespace
+	}
+	return result.Namespace + "/" + result.Service
+}
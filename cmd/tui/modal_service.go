@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+type serviceChoice struct {
+	Port int32
+	Pod  string
+}
+
+func serviceChoiceKey(contextName string, svc kube.Service) string {
+	return fmt.Sprintf("%s/%s/%s", contextName, svc.Namespace, svc.Name)
+}
+
+func (a *App) getServiceChoice(contextName string, svc kube.Service) (serviceChoice, bool) {
+	a.choiceMu.Lock()
+	defer a.choiceMu.Unlock()
+	choice, ok := a.serviceChoices[serviceChoiceKey(contextName, svc)]
+	return choice, ok
+}
+
+func (a *App) setServiceChoice(contextName string, svc kube.Service, choice serviceChoice) {
+	a.choiceMu.Lock()
+	defer a.choiceMu.Unlock()
+	a.serviceChoices[serviceChoiceKey(contextName, svc)] = choice
+}
+
+func (a *App) forgetServiceChoice(contextName string, svc kube.Service) {
+	a.choiceMu.Lock()
+	defer a.choiceMu.Unlock()
+	delete(a.serviceChoices, serviceChoiceKey(contextName, svc))
+}
+
+func (a *App) showServiceDetailsModal(contextName string, svc kube.Service, pods []kube.Pod) {
+	if a.pages.HasPage("servicedetails") {
+		return
+	}
+
+	previous, remembered := a.getServiceChoice(contextName, svc)
+
+	portOptions := make([]string, 0, len(svc.Ports))
+	portIndex := 0
+	if defaultPort := kube.PickHTTPPort(&svc); defaultPort != nil {
+		for i, port := range svc.Ports {
+			if port.Port == defaultPort.Port {
+				portIndex = i
+			}
+		}
+	}
+	for i, port := range svc.Ports {
+		portOptions = append(portOptions, formatServicePort(port))
+		if remembered && port.Port == previous.Port {
+			portIndex = i
+		}
+	}
+
+	podOptions := []string{"Any ready pod"}
+	podIndex := 0
+	for i, pod := range pods {
+		podOptions = append(podOptions, formatPodOption(pod))
+		if remembered && pod.Name == previous.Pod {
+			podIndex = i + 1
+		}
+	}
+
+	portField := tview.NewDropDown().
+		SetLabel("Port: ").
+		SetOptions(portOptions, nil).
+		SetCurrentOption(portIndex)
+	podField := tview.NewDropDown().
+		SetLabel("Pod: ").
+		SetOptions(podOptions, nil).
+		SetCurrentOption(podIndex)
+
+	form := tview.NewForm().
+		AddFormItem(portField).
+		AddFormItem(podField).
+		AddButton("Forward", func() {
+			choice := serviceChoice{}
+			if index, _ := portField.GetCurrentOption(); index >= 0 && index < len(svc.Ports) {
+				choice.Port = svc.Ports[index].Port
+			}
+			if index, _ := podField.GetCurrentOption(); index > 0 && index <= len(pods) {
+				choice.Pod = pods[index-1].Name
+			}
+			a.setServiceChoice(contextName, svc, choice)
+			a.closeServiceDetailsModal()
+			a.registerService(contextName, svc, &choice)
+		}).
+		AddButton("Cancel", func() {
+			a.closeServiceDetailsModal()
+		})
+	form.SetCancelFunc(a.closeServiceDetailsModal)
+	form.SetBackgroundColor(backgroundColor)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			a.closeServiceDetailsModal()
+			return nil
+		}
+		return event
+	})
+
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tview.NewTextView().
+			SetText(fmt.Sprintf("%s/%s (%s)\nThe choice is reused for this service until the app exits; press d to change it.", svc.Namespace, svc.Name, svc.Type)).
+			SetTextAlign(tview.AlignCenter), 2, 0, false).
+		AddItem(form, 0, 1, true)
+
+	contentFlex.SetBorder(true).SetTitle(" Service Details ")
+	contentFlex.SetBackgroundColor(backgroundColor)
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(contentFlex, 0, 2, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+
+	a.pages.AddPage("servicedetails", modal, true, true)
+	a.app.SetFocus(form)
+}
+
+func (a *App) closeServiceDetailsModal() {
+	a.pages.RemovePage("servicedetails")
+	a.app.SetFocus(a.mainView)
+}
+
+func formatServicePort(port kube.ServicePort) string {
+	text := fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+	if port.Name != "" {
+		text = fmt.Sprintf("%s %s", text, port.Name)
+	}
+	if port.TargetPort != 0 && port.TargetPort != port.Port {
+		text = fmt.Sprintf("%s → %d", text, port.TargetPort)
+	}
+	return text
+}
+
+func formatPodOption(pod kube.Pod) string {
+	status := pod.Status
+	if pod.Ready {
+		status += ", ready"
+	} else {
+		status += ", not ready"
+	}
+	return fmt.Sprintf("%s  %s  age %s  restarts %d", pod.Name, status, formatAge(time.Since(pod.Created)), pod.Restarts)
+}
+
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func (a *App) showVirtualHostModal() {
+	if a.pages.HasPage("virtualhost") {
+		return
+	}
+
+	placeholder := "/ <tunnel DNS URL>"
+	if tunnels := a.manager.GetAllDNSTunnels(); len(tunnels) > 0 {
+		placeholder = fmt.Sprintf("/ %s", tunnels[0].DNSURL)
+	}
+
+	hostField := tview.NewInputField().
+		SetLabel("Host: ").
+		SetFieldWidth(40)
+	pathsArea := tview.NewTextArea().
+		SetLabel("Paths: ").
+		SetPlaceholder(placeholder)
+
+	form := tview.NewForm().
+		AddFormItem(hostField).
+		AddFormItem(pathsArea).
+		AddButton("OK", func() {
+			a.confirmVirtualHost(hostField.GetText(), pathsArea.GetText())
+		}).
+		AddButton("Cancel", func() {
+			a.closeVirtualHostModal()
+		})
+	form.SetCancelFunc(a.closeVirtualHostModal)
+	form.SetBackgroundColor(backgroundColor)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			a.closeVirtualHostModal()
+			return nil
+		}
+		return event
+	})
+
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tview.NewTextView().
+			SetText("One path per line: <prefix> <tunnel DNS URL>\n(e.g., /api api.default)").
+			SetTextAlign(tview.AlignCenter), 2, 0, false).
+		AddItem(form, 0, 1, true)
+
+	contentFlex.SetBorder(true).SetTitle(" Virtual Host ")
+	contentFlex.SetBackgroundColor(backgroundColor)
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(contentFlex, 0, 2, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	a.pages.AddPage("virtualhost", modal, true, true)
+	a.app.SetFocus(form)
+}
+
+func (a *App) closeVirtualHostModal() {
+	a.pages.RemovePage("virtualhost")
+	a.app.SetFocus(a.dnsView)
+}
+
+func (a *App) confirmVirtualHost(host, pathsText string) {
+	vh := dns.VirtualHost{Host: strings.TrimSpace(host)}
+	for _, line := range strings.Split(pathsText, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			a.SetMessage(fmt.Sprintf("Invalid path line: %s", strings.TrimSpace(line)))
+			return
+		}
+		vh.Paths = append(vh.Paths, dns.VirtualHostPath{Prefix: fields[0], Tunnel: fields[1]})
+	}
+
+	if err := a.manager.RegisterVirtualHost(vh); err != nil {
+		a.SetMessage(fmt.Sprintf("Failed to add virtual host: %v", err))
+		return
+	}
+
+	a.closeVirtualHostModal()
+	a.UpdateDNSView()
+	a.SetMessage(fmt.Sprintf("Virtual host added: %s", vh.Host))
+}
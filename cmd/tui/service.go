@@ -1,11 +1,14 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sync"
 
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+	"github.com/byoungmin/kube-service-tunnel/internal/kube"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -15,6 +18,20 @@ func (a *App) handleServiceSelection() {
 	if state.IsLoading {
 		return
 	}
+	switch state.Resource {
+	case store.ResourceIngresses:
+		a.handleIngressSelection()
+		return
+	case store.ResourceHTTPRoutes:
+		a.handleHTTPRouteSelection()
+		return
+	case store.ResourceWorkloads:
+		a.handleWorkloadSelection()
+		return
+	case store.ResourcePods:
+		a.handlePodSelection()
+		return
+	}
 
 	selectedNamespace := a.GetSelectedNamespace()
 	if selectedNamespace == "" {
@@ -43,29 +60,106 @@ func (a *App) handleServiceSelection() {
 	svc := services[serviceIndex]
 	contextName := a.GetSelectedContext()
 
-	currentFocus := state.Focus
+	if choice, ok := a.getServiceChoice(contextName, svc); ok {
+		a.registerService(contextName, svc, &choice)
+		return
+	}
+	if svc.IsExternalName() {
+		a.registerService(contextName, svc, nil)
+		return
+	}
 
 	go func() {
-		a.store.SetLoading(true)
+		ctx, done := a.startOperation()
+		pods, err := a.kubeAdapter.ListServicePods(ctx, contextName, svc)
+		done()
+
+		if errors.Is(err, context.Canceled) {
+			a.store.SetMessage(operationCancelledMessage)
+			return
+		}
+		if err != nil || (len(svc.Ports) <= 1 && len(pods) <= 1) {
+			a.registerService(contextName, svc, nil)
+			return
+		}
+		a.app.QueueUpdateDraw(func() {
+			a.showServiceDetailsModal(contextName, svc, pods)
+		})
+	}()
+}
+
+func (a *App) registerService(contextName string, svc kube.Service, choice *serviceChoice) {
+	currentFocus := a.store.GetState().Focus
+
+	go func() {
+		ctx, done := a.startOperation()
 		defer func() {
-			a.store.SetLoading(false)
+			done()
 
 			if currentFocus != "" {
 				go a.store.SetFocus(currentFocus)
 			}
 		}()
 
-		if err := a.manager.RegisterDNSTunnel(contextName, svc.Name, svc.Namespace); err != nil {
+		var err error
+		if choice != nil {
+			err = a.manager.RegisterPinnedDNSTunnel(ctx, contextName, svc.Name, svc.Namespace, choice.Port, choice.Pod)
+		} else {
+			err = a.manager.RegisterDNSTunnel(ctx, contextName, svc.Name, svc.Namespace)
+		}
+		if errors.Is(err, context.Canceled) {
+			a.store.SetMessage(operationCancelledMessage)
+		} else if err != nil {
+			if choice != nil && choice.Pod != "" {
+				a.forgetServiceChoice(contextName, svc)
+				err = fmt.Errorf("%w (press Enter again to choose another pod)", err)
+			}
 			a.store.SetMessage(fmt.Sprintf("Port forwarding failed: %v", err))
 		} else {
-			a.app.QueueUpdateDraw(func() {
-				a.UpdateDNSView()
-			})
 			a.store.SetMessage(fmt.Sprintf("Port forwarding started: %s.%s", svc.Name, svc.Namespace))
 		}
 	}()
 }
 
+func (a *App) showSelectedServiceDetails() {
+	state := a.store.GetState()
+	if state.IsLoading || state.Resource != store.ResourceServices {
+		return
+	}
+
+	services := a.GetServices()
+	selectedRow, _ := a.mainView.GetSelection()
+	index := selectedRow - 1
+	if index < 0 || index >= len(services) {
+		a.store.SetMessage("Please select a service (use arrow keys to navigate, then press d)")
+		return
+	}
+
+	svc := services[index]
+	if svc.IsExternalName() {
+		a.store.SetMessage(fmt.Sprintf("Service %s is an ExternalName service without pods", svc.Name))
+		return
+	}
+	contextName := a.GetSelectedContext()
+
+	go func() {
+		ctx, done := a.startOperation()
+		pods, err := a.kubeAdapter.ListServicePods(ctx, contextName, svc)
+		done()
+
+		if errors.Is(err, context.Canceled) {
+			a.store.SetMessage(operationCancelledMessage)
+			return
+		}
+		if err != nil {
+			a.store.SetMessage(fmt.Sprintf("Error fetching pods for service %s: %v", svc.Name, err))
+		}
+		a.app.QueueUpdateDraw(func() {
+			a.showServiceDetailsModal(contextName, svc, pods)
+		})
+	}()
+}
+
 func (a *App) RenderServiceView() *tview.Table {
 	mainView := tview.NewTable()
 	mainView.SetBorders(false).
@@ -83,7 +177,24 @@ func (a *App) RenderServiceView() *tview.Table {
 	a.store.Subscribe(func(s store.State) {
 		mu.Lock()
 		defer mu.Unlock()
-		if !reflect.DeepEqual(prevState.Services, s.Services) {
+		if prevState.Resource != s.Resource || prevState.SelectedContext != s.SelectedContext || prevState.SelectedNamespace != s.SelectedNamespace {
+			switch s.Resource {
+			case store.ResourceIngresses:
+				go a.loadIngresses(s.SelectedContext, s.SelectedNamespace)
+			case store.ResourceHTTPRoutes:
+				go a.loadHTTPRoutes(s.SelectedContext, s.SelectedNamespace)
+			case store.ResourceWorkloads:
+				go a.loadWorkloads(s.SelectedContext, s.SelectedNamespace)
+			case store.ResourcePods:
+				go a.loadPods(s.SelectedContext, s.SelectedNamespace)
+			}
+		}
+		if !reflect.DeepEqual(prevState.Services, s.Services) ||
+			!reflect.DeepEqual(prevState.Ingresses, s.Ingresses) ||
+			!reflect.DeepEqual(prevState.HTTPRoutes, s.HTTPRoutes) ||
+			!reflect.DeepEqual(prevState.Workloads, s.Workloads) ||
+			!reflect.DeepEqual(prevState.Pods, s.Pods) ||
+			prevState.Resource != s.Resource {
 			a.app.QueueUpdateDraw(func() {
 				a.UpdateMainView()
 			})
@@ -105,6 +216,27 @@ func (a *App) handleServiceInput(event *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyEnter:
 		a.handleServiceSelection()
 		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 's':
+			go a.store.SetResource(store.ResourceServices)
+			return nil
+		case 'i':
+			go a.store.SetResource(store.ResourceIngresses)
+			return nil
+		case 'r':
+			go a.store.SetResource(store.ResourceHTTPRoutes)
+			return nil
+		case 'w':
+			go a.store.SetResource(store.ResourceWorkloads)
+			return nil
+		case 'p':
+			go a.store.SetResource(store.ResourcePods)
+			return nil
+		case 'd':
+			a.showSelectedServiceDetails()
+			return nil
+		}
 	}
 	return event
 }
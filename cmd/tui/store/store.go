@@ -2,9 +2,11 @@ package store
 
 import (
 	"reflect"
+	"slices"
 	"sort"
 	"sync"
 
+	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
 	"github.com/byoungmin/kube-service-tunnel/internal/kube"
 )
 
@@ -17,11 +19,27 @@ const (
 	FocusTunnels    FocusArea = "tunnels"
 )
 
+type ResourceKind string
+
+const (
+	ResourceServices   ResourceKind = "services"
+	ResourceIngresses  ResourceKind = "ingresses"
+	ResourceHTTPRoutes ResourceKind = "httproutes"
+	ResourceWorkloads  ResourceKind = "workloads"
+	ResourcePods       ResourceKind = "pods"
+)
+
 type State struct {
 	ResourceMap       map[string]map[string][]kube.Service // {Context: {Namespace: [Services]}}
 	Contexts          []kube.Context
 	Namespaces        []string
 	Services          []kube.Service
+	Ingresses         []kube.Ingress
+	HTTPRoutes        []kube.HTTPRoute
+	Workloads         []kube.Workload
+	Pods              []kube.Pod
+	Tunnels           []dns.DNSTunnel
+	Resource          ResourceKind
 	SelectedContext   string
 	SelectedNamespace string
 	IsLoading         bool
@@ -68,6 +86,16 @@ func (store *Store) setState(updateFn func(*State) bool) {
 	copy(stateCopy.Namespaces, store.state.Namespaces)
 	stateCopy.Services = make([]kube.Service, len(store.state.Services))
 	copy(stateCopy.Services, store.state.Services)
+	stateCopy.Ingresses = make([]kube.Ingress, len(store.state.Ingresses))
+	copy(stateCopy.Ingresses, store.state.Ingresses)
+	stateCopy.HTTPRoutes = make([]kube.HTTPRoute, len(store.state.HTTPRoutes))
+	copy(stateCopy.HTTPRoutes, store.state.HTTPRoutes)
+	stateCopy.Workloads = make([]kube.Workload, len(store.state.Workloads))
+	copy(stateCopy.Workloads, store.state.Workloads)
+	stateCopy.Pods = make([]kube.Pod, len(store.state.Pods))
+	copy(stateCopy.Pods, store.state.Pods)
+	stateCopy.Tunnels = make([]dns.DNSTunnel, len(store.state.Tunnels))
+	copy(stateCopy.Tunnels, store.state.Tunnels)
 
 	currentListeners := make([]func(State), len(store.listeners))
 	copy(currentListeners, store.listeners)
@@ -151,6 +179,10 @@ func (store *Store) SetSelectedContext(contextName string) {
 		state.SelectedNamespace = ""
 		state.Namespaces = nil
 		state.Services = nil
+		state.Ingresses = nil
+		state.HTTPRoutes = nil
+		state.Workloads = nil
+		state.Pods = nil
 		return true
 	})
 }
@@ -162,6 +194,10 @@ func (store *Store) SetSelectedContextWithResources(contextName string) {
 		}
 
 		state.SelectedContext = contextName
+		state.Ingresses = nil
+		state.HTTPRoutes = nil
+		state.Workloads = nil
+		state.Pods = nil
 
 		ctxMap, ok := state.ResourceMap[contextName]
 		if !ok {
@@ -206,6 +242,10 @@ func (store *Store) SetSelectedNamespace(namespace string) {
 			return false
 		}
 		state.SelectedNamespace = namespace
+		state.Ingresses = nil
+		state.HTTPRoutes = nil
+		state.Workloads = nil
+		state.Pods = nil
 
 		if ctxMap, ok := state.ResourceMap[state.SelectedContext]; ok {
 			state.Services = ctxMap[namespace]
@@ -225,6 +265,98 @@ func (store *Store) SetServices(services []kube.Service) {
 	})
 }
 
+func (store *Store) SetIngresses(ingresses []kube.Ingress) {
+	store.setState(func(state *State) bool {
+		if reflect.DeepEqual(state.Ingresses, ingresses) {
+			return false
+		}
+		state.Ingresses = ingresses
+		return true
+	})
+}
+
+func (store *Store) SetHTTPRoutes(routes []kube.HTTPRoute) {
+	store.setState(func(state *State) bool {
+		if reflect.DeepEqual(state.HTTPRoutes, routes) {
+			return false
+		}
+		state.HTTPRoutes = routes
+		return true
+	})
+}
+
+func (store *Store) SetWorkloads(workloads []kube.Workload) {
+	store.setState(func(state *State) bool {
+		if reflect.DeepEqual(state.Workloads, workloads) {
+			return false
+		}
+		state.Workloads = workloads
+		return true
+	})
+}
+
+func (store *Store) SetPods(pods []kube.Pod) {
+	store.setState(func(state *State) bool {
+		if reflect.DeepEqual(state.Pods, pods) {
+			return false
+		}
+		state.Pods = pods
+		return true
+	})
+}
+
+func (store *Store) SetTunnels(tunnels []dns.DNSTunnel) {
+	store.setState(func(state *State) bool {
+		if reflect.DeepEqual(state.Tunnels, tunnels) {
+			return false
+		}
+		state.Tunnels = tunnels
+		return true
+	})
+}
+
+func (store *Store) PutTunnel(tunnel dns.DNSTunnel) {
+	store.setState(func(state *State) bool {
+		tunnels := slices.Clone(state.Tunnels)
+		index := slices.IndexFunc(tunnels, func(t dns.DNSTunnel) bool {
+			return t.DNSURL == tunnel.DNSURL
+		})
+		if index == -1 {
+			state.Tunnels = append(tunnels, tunnel)
+			return true
+		}
+		if reflect.DeepEqual(tunnels[index], tunnel) {
+			return false
+		}
+		tunnels[index] = tunnel
+		state.Tunnels = tunnels
+		return true
+	})
+}
+
+func (store *Store) RemoveTunnel(dnsURL string) {
+	store.setState(func(state *State) bool {
+		tunnels := slices.DeleteFunc(slices.Clone(state.Tunnels), func(t dns.DNSTunnel) bool {
+			return t.DNSURL == dnsURL
+		})
+		if len(tunnels) == len(state.Tunnels) {
+			return false
+		}
+		state.Tunnels = tunnels
+		return true
+	})
+}
+
+func (store *Store) SetResource(resource ResourceKind) {
+	store.setState(func(state *State) bool {
+		if state.Resource == resource {
+			return false
+		}
+		state.Resource = resource
+		return true
+	})
+}
+
 func (store *Store) SetFocus(focus FocusArea) {
 	store.setState(func(state *State) bool {
 		if state.Focus == focus {
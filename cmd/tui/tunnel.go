@@ -2,48 +2,268 @@ package tui
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
+	"time"
 
+	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+	proxyadapter "github.com/byoungmin/kube-service-tunnel/internal/proxy"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
-func (a *App) handleTunnelDeletion() {
+type tunnelRow struct {
+	tunnel      *dns.DNSTunnel
+	virtualHost *dns.VirtualHost
+}
+
+func (r tunnelRow) host() string {
+	if r.virtualHost != nil {
+		return r.virtualHost.Host
+	}
+	return r.tunnel.DNSURL
+}
+
+func (r tunnelRow) capturing() bool {
+	if r.virtualHost != nil {
+		return r.virtualHost.Capturing
+	}
+	return r.tunnel.Capturing
+}
+
+func (a *App) getTunnelRows() []tunnelRow {
+	tunnels := a.store.GetState().Tunnels
+	virtualHosts := a.manager.GetAllVirtualHosts()
+
+	rows := make([]tunnelRow, 0, len(tunnels)+len(virtualHosts))
+	for i := range tunnels {
+		rows = append(rows, tunnelRow{tunnel: &tunnels[i]})
+	}
+	for i := range virtualHosts {
+		rows = append(rows, tunnelRow{virtualHost: &virtualHosts[i]})
+	}
+	return rows
+}
+
+func (a *App) refreshTunnels() {
+	a.store.SetTunnels(a.manager.GetAllDNSTunnels())
+}
+
+func (a *App) SetupTunnelSubscription() {
+	var prevState store.State
+	var mu sync.Mutex
+	a.store.Subscribe(func(s store.State) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !reflect.DeepEqual(s.Tunnels, prevState.Tunnels) {
+			a.app.QueueUpdateDraw(func() {
+				a.UpdateDNSView()
+			})
+		}
+		prevState = s
+	})
+}
+
+func (a *App) watchTunnelEvents() {
+	events, unsubscribe := a.manager.Subscribe()
+	defer unsubscribe()
+
+	a.refreshTunnels()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			previous, known := a.findStoredTunnel(event.DNSURL)
+			a.applyTunnelEvent(event)
+			if message := tunnelEventMessage(event, previous, known); message != "" {
+				a.store.SetMessage(message)
+			}
+		}
+	}
+}
+
+func (a *App) applyTunnelEvent(event dns.TunnelEvent) {
+	switch {
+	case event.Type == dns.TunnelEventRemoved:
+		a.store.RemoveTunnel(event.DNSURL)
+	case event.Tunnel.DNSURL != "":
+		a.store.PutTunnel(event.Tunnel)
+	}
+}
+
+func (a *App) findStoredTunnel(dnsURL string) (dns.DNSTunnel, bool) {
+	if dnsURL == "" {
+		return dns.DNSTunnel{}, false
+	}
+	for _, tunnel := range a.store.GetState().Tunnels {
+		if tunnel.DNSURL == dnsURL {
+			return tunnel, true
+		}
+	}
+	return dns.DNSTunnel{}, false
+}
+
+func tunnelEventMessage(event dns.TunnelEvent, previous dns.DNSTunnel, known bool) string {
+	switch event.Type {
+	case dns.TunnelEventDegraded:
+		return fmt.Sprintf("Tunnel %s lost pod %s and keeps serving from the remaining pods", event.DNSURL, event.Pod)
+	case dns.TunnelEventReconnecting:
+		return fmt.Sprintf("Tunnel %s lost its port forward, reconnecting", event.DNSURL)
+	case dns.TunnelEventError:
+		if event.State != "" {
+			return fmt.Sprintf("Tunnel %s failed: %v", event.DNSURL, event.Err)
+		}
+	case dns.TunnelEventWarning:
+		if event.DNSURL != "" {
+			return fmt.Sprintf("Tunnel %s: %v", event.DNSURL, event.Err)
+		}
+		return fmt.Sprintf("Warning: %v", event.Err)
+	case dns.TunnelEventReady:
+		if known && event.State == dns.TunnelStateActive && previous.State != dns.TunnelStateActive && previous.State != dns.TunnelStateIdle {
+			return fmt.Sprintf("Tunnel %s is ready again", event.DNSURL)
+		}
+	}
+	return ""
+}
+
+func (a *App) watchVirtualHostRows() {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	previous := a.manager.GetAllVirtualHosts()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		virtualHosts := a.manager.GetAllVirtualHosts()
+		if reflect.DeepEqual(previous, virtualHosts) {
+			continue
+		}
+		previous = virtualHosts
+		a.app.QueueUpdateDraw(func() {
+			a.UpdateDNSView()
+		})
+	}
+}
+
+func (a *App) getSelectedTunnelRow() (tunnelRow, bool) {
 	selectedRow, _ := a.dnsView.GetSelection()
 	if selectedRow <= 0 {
 		a.SetMessage("Please select a local DNS tunnel")
-		return
+		return tunnelRow{}, false
 	}
 
-	entries := a.manager.GetAllDNSTunnels()
-	if len(entries) == 0 {
-		a.SetMessage("No local DNS tunnels to delete")
-		return
+	rows := a.getTunnelRows()
+	if len(rows) == 0 {
+		a.SetMessage("No local DNS tunnels found")
+		return tunnelRow{}, false
 	}
 
 	entryIndex := selectedRow - 1
-	if entryIndex < 0 || entryIndex >= len(entries) {
+	if entryIndex < 0 || entryIndex >= len(rows) {
 		a.SetMessage("Invalid local DNS tunnel selection")
+		return tunnelRow{}, false
+	}
+
+	return rows[entryIndex], true
+}
+
+func (a *App) getSelectedTunnel() (dns.DNSTunnel, bool) {
+	row, ok := a.getSelectedTunnelRow()
+	if !ok {
+		return dns.DNSTunnel{}, false
+	}
+	if row.tunnel == nil {
+		a.SetMessage("Not available for virtual hosts")
+		return dns.DNSTunnel{}, false
+	}
+	return *row.tunnel, true
+}
+
+func (a *App) handleTunnelDeletion() {
+	row, ok := a.getSelectedTunnelRow()
+	if !ok {
 		return
 	}
 
-	entry := entries[entryIndex]
+	host := row.host()
+	isVirtualHost := row.virtualHost != nil
 
 	go func() {
 		a.store.SetLoading(true)
 		defer a.store.SetLoading(false)
 
-		if err := a.manager.UnregisterDNSTunnel(entry.DNSURL); err != nil {
+		var err error
+		if isVirtualHost {
+			err = a.manager.UnregisterVirtualHost(host)
+		} else {
+			err = a.manager.UnregisterDNSTunnel(host)
+		}
+
+		if err != nil {
 			a.store.SetMessage(fmt.Sprintf("Failed to stop local DNS tunnel: %v", err))
 		} else {
-			a.app.QueueUpdateDraw(func() {
-				a.UpdateDNSView()
-			})
-			a.store.SetMessage(fmt.Sprintf("Local DNS tunnel stopped: %s", entry.DNSURL))
+			if isVirtualHost {
+				a.app.QueueUpdateDraw(func() {
+					a.UpdateDNSView()
+				})
+			}
+			a.store.SetMessage(fmt.Sprintf("Local DNS tunnel stopped: %s", host))
 		}
 	}()
 }
 
+func (a *App) handleTunnelProtocolChange() {
+	entry, ok := a.getSelectedTunnel()
+	if !ok {
+		return
+	}
+
+	protocol, err := proxyadapter.ParseProtocol(entry.Protocol)
+	if err != nil {
+		protocol = proxyadapter.ProtocolHTTP1
+	}
+	next := protocol.Next()
+
+	if err := a.manager.SetTunnelProtocol(entry.DNSURL, string(next)); err != nil {
+		a.SetMessage(fmt.Sprintf("Failed to change protocol: %v", err))
+		return
+	}
+
+	a.SetMessage(fmt.Sprintf("Protocol for %s set to %s", entry.DNSURL, next))
+}
+
+func (a *App) handleTunnelCaptureToggle() {
+	row, ok := a.getSelectedTunnelRow()
+	if !ok {
+		return
+	}
+
+	host := row.host()
+	enabled := !row.capturing()
+	path, err := a.manager.SetTunnelCapture(host, enabled)
+	if err != nil {
+		a.SetMessage(fmt.Sprintf("HAR capture failed: %v", err))
+		return
+	}
+
+	a.UpdateDNSView()
+	if enabled {
+		a.SetMessage(fmt.Sprintf("HAR capture started for %s: %s", host, path))
+	} else {
+		a.SetMessage(fmt.Sprintf("HAR capture saved for %s: %s", host, path))
+	}
+}
+
 func (a *App) RenderTunnelView() *tview.Table {
 	dnsView := tview.NewTable()
 	dnsView.SetBorders(false).
@@ -71,6 +291,21 @@ func (a *App) handleTunnelInput(event *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyDelete:
 		a.handleTunnelDeletion()
 		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'p':
+			a.handleTunnelProtocolChange()
+			return nil
+		case 'i':
+			a.handleTunnelInspect()
+			return nil
+		case 'h':
+			a.handleTunnelCaptureToggle()
+			return nil
+		case 'v':
+			a.showVirtualHostModal()
+			return nil
+		}
 	}
 	return event
 }
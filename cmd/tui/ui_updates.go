@@ -1,7 +1,12 @@
 package tui
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/dns"
 	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
 	"github.com/byoungmin/kube-service-tunnel/internal/kube"
 	"github.com/rivo/tview"
 )
@@ -95,6 +100,34 @@ func (a *App) InitMainView() {
 			SetExpansion(expansion)
 	}
 
+	switch a.store.GetState().Resource {
+	case store.ResourceIngresses:
+		a.mainView.SetTitle(" Ingresses ")
+		a.mainView.SetCell(0, 0, headerCell("Name", 2))
+		a.mainView.SetCell(0, 1, headerCell("Hosts", 2))
+		a.mainView.SetCell(0, 2, headerCell("Class", 1))
+		return
+	case store.ResourceHTTPRoutes:
+		a.mainView.SetTitle(" HTTPRoutes ")
+		a.mainView.SetCell(0, 0, headerCell("Name", 2))
+		a.mainView.SetCell(0, 1, headerCell("Hostnames", 2))
+		a.mainView.SetCell(0, 2, headerCell("Rules", 1))
+		return
+	case store.ResourceWorkloads:
+		a.mainView.SetTitle(" Workloads ")
+		a.mainView.SetCell(0, 0, headerCell("Name", 2))
+		a.mainView.SetCell(0, 1, headerCell("Kind", 1))
+		a.mainView.SetCell(0, 2, headerCell("Ready", 1))
+		return
+	case store.ResourcePods:
+		a.mainView.SetTitle(" Pods ")
+		a.mainView.SetCell(0, 0, headerCell("Name", 2))
+		a.mainView.SetCell(0, 1, headerCell("Status", 1))
+		a.mainView.SetCell(0, 2, headerCell("Ports", 1))
+		return
+	}
+
+	a.mainView.SetTitle(" Services ")
 	a.mainView.SetCell(0, 0, headerCell("Name", 2))
 	a.mainView.SetCell(0, 1, headerCell("ClusterIP", 1))
 	a.mainView.SetCell(0, 2, headerCell("Type", 1))
@@ -111,12 +144,59 @@ func (a *App) UpdateMainView() {
 		return newTableCell(text).SetExpansion(expansion)
 	}
 
+	switch a.store.GetState().Resource {
+	case store.ResourceIngresses:
+		for i, ing := range a.GetIngresses() {
+			row := i + 1
+			a.mainView.SetCell(row, 0, dataCell(ing.Name, 2))
+			a.mainView.SetCell(row, 1, dataCell(strings.Join(ing.Hosts(), ", "), 2))
+			a.mainView.SetCell(row, 2, dataCell(ing.ClassName, 1))
+		}
+		return
+	case store.ResourceHTTPRoutes:
+		for i, route := range a.GetHTTPRoutes() {
+			row := i + 1
+			a.mainView.SetCell(row, 0, dataCell(route.Name, 2))
+			a.mainView.SetCell(row, 1, dataCell(strings.Join(route.Hostnames, ", "), 2))
+			a.mainView.SetCell(row, 2, dataCell(fmt.Sprintf("%d", len(route.Rules)), 1))
+		}
+		return
+	case store.ResourceWorkloads:
+		for i, workload := range a.GetWorkloads() {
+			row := i + 1
+			a.mainView.SetCell(row, 0, dataCell(workload.Name, 2))
+			a.mainView.SetCell(row, 1, dataCell(workload.Kind, 1))
+			a.mainView.SetCell(row, 2, dataCell(fmt.Sprintf("%d/%d", workload.ReadyReplicas, workload.Replicas), 1))
+		}
+		return
+	case store.ResourcePods:
+		for i, pod := range a.GetPods() {
+			row := i + 1
+			status := pod.Status
+			if pod.Status == "Running" && !pod.Ready {
+				status += " (not ready)"
+			}
+			ports := make([]string, 0, len(pod.Ports))
+			for _, port := range pod.Ports {
+				ports = append(ports, fmt.Sprintf("%d", port.ContainerPort))
+			}
+			a.mainView.SetCell(row, 0, dataCell(pod.Name, 2))
+			a.mainView.SetCell(row, 1, dataCell(status, 1))
+			a.mainView.SetCell(row, 2, dataCell(strings.Join(ports, ","), 1))
+		}
+		return
+	}
+
 	services := a.GetServices()
 	for i, svc := range services {
 		row := i + 1
 		a.mainView.SetCell(row, 0, dataCell(svc.Name, 2))
 		a.mainView.SetCell(row, 1, dataCell(svc.ClusterIP, 1))
-		a.mainView.SetCell(row, 2, dataCell(svc.Type, 1))
+		serviceType := svc.Type
+		if svc.IsExternalName() {
+			serviceType = fmt.Sprintf("%s (%s)", svc.Type, svc.ExternalName)
+		}
+		a.mainView.SetCell(row, 2, dataCell(serviceType, 1))
 	}
 }
 
@@ -136,10 +216,13 @@ func (a *App) UpdateDNSView() {
 	a.dnsView.SetCell(0, 0, headerCell("Context", 1))
 	a.dnsView.SetCell(0, 1, headerCell("Namespace", 1))
 	a.dnsView.SetCell(0, 2, headerCell("DNS URL", 2))
+	a.dnsView.SetCell(0, 3, headerCell("Protocol", 1))
+	a.dnsView.SetCell(0, 4, headerCell("State", 1))
+	a.dnsView.SetCell(0, 5, headerCell("HAR", 1))
 
-	entries := a.manager.GetAllDNSTunnels()
+	rows := a.getTunnelRows()
 
-	if len(entries) == 0 {
+	if len(rows) == 0 {
 		a.dnsView.SetCell(1, 0, newTableCell("No tunnel entries").SetTextColor(textColor).SetExpansion(1))
 		return
 	}
@@ -148,14 +231,80 @@ func (a *App) UpdateDNSView() {
 		return newTableCell(text).SetExpansion(expansion)
 	}
 
-	for i, entry := range entries {
+	for i, entry := range rows {
 		row := i + 1
-		a.dnsView.SetCell(row, 0, dataCell(entry.Context, 1))
-		a.dnsView.SetCell(row, 1, dataCell(entry.Namespace, 1))
-		a.dnsView.SetCell(row, 2, dataCell(entry.DNSURL, 2))
+		capture := ""
+		if entry.capturing() {
+			capture = "rec"
+		}
+		a.dnsView.SetCell(row, 5, dataCell(capture, 1))
+
+		if entry.virtualHost != nil {
+			source := entry.virtualHost.Source
+			if source == "" {
+				source = "virtual host"
+			}
+			a.dnsView.SetCell(row, 0, dataCell(source, 1))
+			a.dnsView.SetCell(row, 1, dataCell("", 1))
+			a.dnsView.SetCell(row, 2, dataCell(formatVirtualHost(*entry.virtualHost), 2))
+			a.dnsView.SetCell(row, 3, dataCell("", 1))
+			state := "active"
+			if !entry.virtualHost.Active {
+				state = "inactive"
+			}
+			a.dnsView.SetCell(row, 4, dataCell(state, 1))
+			continue
+		}
+
+		a.dnsView.SetCell(row, 0, dataCell(entry.tunnel.Context, 1))
+		a.dnsView.SetCell(row, 1, dataCell(entry.tunnel.Namespace, 1))
+		a.dnsView.SetCell(row, 2, dataCell(entry.tunnel.DNSURL, 2))
+		a.dnsView.SetCell(row, 3, dataCell(entry.tunnel.Protocol, 1))
+		state := entry.tunnel.State
+		if len(entry.tunnel.Backends) > 0 {
+			state = fmt.Sprintf("%s (%d pods)", state, len(entry.tunnel.Backends))
+		}
+		if entry.tunnel.Kind == kube.WorkloadKindDeployment || entry.tunnel.Kind == kube.WorkloadKindStatefulSet {
+			state = fmt.Sprintf("%s (%s)", state, entry.tunnel.Pod)
+		}
+		if entry.tunnel.Transport != "" {
+			state = fmt.Sprintf("%s (%s)", state, entry.tunnel.Transport)
+		}
+		if entry.tunnel.Relay {
+			state += " (relay)"
+		}
+		if entry.tunnel.Address != "" {
+			state = fmt.Sprintf("%s (%s)", state, entry.tunnel.Address)
+		}
+		a.dnsView.SetCell(row, 4, dataCell(state, 1))
 	}
 }
 
+func formatVirtualHost(vh dns.VirtualHost) string {
+	paths := make([]string, 0, len(vh.Paths))
+	for _, path := range vh.Paths {
+		match := path.Prefix
+		if path.Exact {
+			match = "=" + match
+		}
+		for _, header := range path.Headers {
+			match += fmt.Sprintf("[%s]", header.Name)
+		}
+
+		target := path.Tunnel
+		if len(path.Backends) > 0 {
+			targets := make([]string, 0, len(path.Backends))
+			for _, backend := range path.Backends {
+				targets = append(targets, fmt.Sprintf("%s:%d", backend.Tunnel, backend.Weight))
+			}
+			target = strings.Join(targets, "|")
+		}
+		paths = append(paths, fmt.Sprintf("%s→%s", match, target))
+	}
+
+	return tview.Escape(fmt.Sprintf("%s (%s)", vh.Host, strings.Join(paths, ", ")))
+}
+
 func (a *App) UpdateHeader() {
 	if a.header == nil {
 		return
@@ -181,6 +330,9 @@ func (a *App) UpdateContextList() {
 	selectedContext := a.GetSelectedContext()
 	for i, ctx := range contexts {
 		text := ctx.Name
+		if a.manager.ContextTransport(ctx.Name) == config.TransportAPIServer {
+			text += " (api-server)"
+		}
 		if ctx.Name == selectedContext {
 			text += " (current)"
 		}
@@ -283,6 +435,22 @@ func (a *App) GetServices() []kube.Service {
 	return a.store.GetState().Services
 }
 
+func (a *App) GetIngresses() []kube.Ingress {
+	return a.store.GetState().Ingresses
+}
+
+func (a *App) GetHTTPRoutes() []kube.HTTPRoute {
+	return a.store.GetState().HTTPRoutes
+}
+
+func (a *App) GetWorkloads() []kube.Workload {
+	return a.store.GetState().Workloads
+}
+
+func (a *App) GetPods() []kube.Pod {
+	return a.store.GetState().Pods
+}
+
 func (a *App) SetSelectedContext(contextName string) error {
 	a.store.SetSelectedContextWithResources(contextName)
 	return nil
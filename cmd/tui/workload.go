@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/byoungmin/kube-service-tunnel/cmd/tui/store"
+)
+
+func (a *App) loadWorkloads(contextName, namespace string) {
+	if contextName == "" || namespace == "" {
+		return
+	}
+
+	workloads, err := a.kubeAdapter.ListWorkloads(a.ctx, namespace, contextName)
+	if err != nil {
+		a.store.SetMessage(fmt.Sprintf("Error fetching workloads: %v", err))
+		return
+	}
+
+	state := a.store.GetState()
+	if state.SelectedContext != contextName || state.SelectedNamespace != namespace {
+		return
+	}
+	a.store.SetWorkloads(workloads)
+	if len(workloads) == 0 {
+		a.store.SetMessage(fmt.Sprintf("No Deployments or StatefulSets found in namespace %s", namespace))
+	}
+}
+
+func (a *App) loadPods(contextName, namespace string) {
+	if contextName == "" || namespace == "" {
+		return
+	}
+
+	pods, err := a.kubeAdapter.ListPods(a.ctx, namespace, contextName)
+	if err != nil {
+		a.store.SetMessage(fmt.Sprintf("Error fetching pods: %v", err))
+		return
+	}
+
+	state := a.store.GetState()
+	if state.SelectedContext != contextName || state.SelectedNamespace != namespace {
+		return
+	}
+	a.store.SetPods(pods)
+	if len(pods) == 0 {
+		a.store.SetMessage(fmt.Sprintf("No pods found in namespace %s", namespace))
+	}
+}
+
+func (a *App) handleWorkloadSelection() {
+	workloads := a.GetWorkloads()
+	if len(workloads) == 0 {
+		a.store.SetMessage("No workloads found in selected namespace")
+		return
+	}
+
+	selectedRow, _ := a.mainView.GetSelection()
+	index := selectedRow - 1
+	if index < 0 || index >= len(workloads) {
+		a.store.SetMessage("Please select a workload (use arrow keys to navigate, then press Enter)")
+		return
+	}
+
+	workload := workloads[index]
+	contextName := a.GetSelectedContext()
+
+	go func() {
+		ctx, done := a.startOperation()
+		defer func() {
+			done()
+			go a.store.SetFocus(store.FocusServices)
+		}()
+
+		dnsURLs, err := a.manager.RegisterWorkloadTunnel(ctx, contextName, workload.Namespace, workload.Kind, workload.Name)
+		if err != nil {
+			a.store.SetMessage(operationMessage(workload.Kind+" tunnel failed: %v", err))
+			return
+		}
+
+		a.store.SetMessage(fmt.Sprintf("%s tunneled: %s (%s)", workload.Kind, workload.Name, strings.Join(dnsURLs, ", ")))
+	}()
+}
+
+func (a *App) handlePodSelection() {
+	pods := a.GetPods()
+	if len(pods) == 0 {
+		a.store.SetMessage("No pods found in selected namespace")
+		return
+	}
+
+	selectedRow, _ := a.mainView.GetSelection()
+	index := selectedRow - 1
+	if index < 0 || index >= len(pods) {
+		a.store.SetMessage("Please select a pod (use arrow keys to navigate, then press Enter)")
+		return
+	}
+
+	pod := pods[index]
+	contextName := a.GetSelectedContext()
+
+	go func() {
+		ctx, done := a.startOperation()
+		defer func() {
+			done()
+			go a.store.SetFocus(store.FocusServices)
+		}()
+
+		dnsURLs, err := a.manager.RegisterPodTunnel(ctx, contextName, pod.Namespace, pod.Name)
+		if err != nil {
+			a.store.SetMessage(operationMessage("Pod tunnel failed: %v", err))
+			return
+		}
+
+		a.store.SetMessage(fmt.Sprintf("Pod tunneled: %s (%s)", pod.Name, strings.Join(dnsURLs, ", ")))
+	}()
+}
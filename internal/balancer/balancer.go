@@ -0,0 +1,289 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Policy string
+
+const (
+	PolicyRoundRobin       Policy = "round-robin"
+	PolicyLeastConnections Policy = "least-connections"
+)
+
+var Policies = []Policy{PolicyRoundRobin, PolicyLeastConnections}
+
+var ErrNoBackends = errors.New("no healthy backends")
+
+const (
+	dialTimeout     = 5 * time.Second
+	probeBackoff    = time.Second
+	probeMaxBackoff = 30 * time.Second
+)
+
+func ParsePolicy(value string) (Policy, error) {
+	if value == "" {
+		return PolicyRoundRobin, nil
+	}
+	for _, policy := range Policies {
+		if string(policy) == value {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown load balancing policy: %s", value)
+}
+
+type Backend struct {
+	Name      string
+	Addr      string
+	active    atomic.Int64
+	unhealthy bool
+}
+
+type Pool struct {
+	policy    Policy
+	backends  []*Backend
+	next      int
+	onRemove  func(*Backend)
+	listeners []net.Listener
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+}
+
+func NewPool(policy Policy, backends []*Backend, onRemove func(*Backend)) *Pool {
+	return &Pool{
+		policy:   policy,
+		backends: backends,
+		onRemove: onRemove,
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *Pool) Backends() []*Backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Backend(nil), p.backends...)
+}
+
+func (p *Pool) pick(exclude map[*Backend]bool) *Backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var candidates, unhealthy []*Backend
+	for _, b := range p.backends {
+		switch {
+		case exclude[b]:
+		case b.unhealthy:
+			unhealthy = append(unhealthy, b)
+		default:
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = unhealthy
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	start := p.next % len(candidates)
+	p.next++
+	if p.policy != PolicyLeastConnections {
+		return candidates[start]
+	}
+
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		b := candidates[(start+i)%len(candidates)]
+		if b.active.Load() < best.active.Load() {
+			best = b
+		}
+	}
+	return best
+}
+
+func (p *Pool) Add(b *Backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.backends = append(p.backends, b)
+}
+
+func (p *Pool) Remove(b *Backend) {
+	p.mu.Lock()
+	removed := false
+	for i, existing := range p.backends {
+		if existing == b {
+			p.backends = append(p.backends[:i], p.backends[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if removed && p.onRemove != nil {
+		p.onRemove(b)
+	}
+}
+
+func (p *Pool) Dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	tried := make(map[*Backend]bool)
+
+	for {
+		b := p.pick(tried)
+		if b == nil {
+			return nil, ErrNoBackends
+		}
+		tried[b] = true
+
+		dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+		conn, err := dialer.DialContext(dialCtx, "tcp", b.Addr)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			p.markUnhealthy(b)
+			continue
+		}
+
+		p.markHealthy(b)
+		b.active.Add(1)
+		return &trackedConn{Conn: conn, backend: b}, nil
+	}
+}
+
+func (p *Pool) markHealthy(b *Backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.unhealthy = false
+}
+
+func (p *Pool) markUnhealthy(b *Backend) {
+	p.mu.Lock()
+	probe := !b.unhealthy && slices.Contains(p.backends, b)
+	b.unhealthy = true
+	p.mu.Unlock()
+
+	if probe {
+		go p.probe(b)
+	}
+}
+
+func (p *Pool) probe(b *Backend) {
+	backoff := probeBackoff
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		p.mu.Lock()
+		pending := b.unhealthy && slices.Contains(p.backends, b)
+		p.mu.Unlock()
+		if !pending {
+			return
+		}
+
+		conn, err := net.DialTimeout("tcp", b.Addr, dialTimeout)
+		if err == nil {
+			conn.Close()
+			p.markHealthy(b)
+			return
+		}
+		backoff = min(backoff*2, probeMaxBackoff)
+	}
+}
+
+func (p *Pool) Serve(listeners ...net.Listener) {
+	p.mu.Lock()
+	p.listeners = append(p.listeners, listeners...)
+	p.mu.Unlock()
+
+	for _, listener := range listeners {
+		go p.serve(listener)
+	}
+}
+
+func (p *Pool) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *Pool) handle(conn net.Conn) {
+	defer conn.Close()
+
+	upstream, err := p.Dial(context.Background())
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		closeWrite(upstream)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		closeWrite(conn)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
+
+func (p *Pool) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+
+	p.mu.Lock()
+	listeners := p.listeners
+	p.listeners = nil
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, listener := range listeners {
+		if err := listener.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type trackedConn struct {
+	net.Conn
+	backend *Backend
+	once    sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.backend.active.Add(-1) })
+	return c.Conn.Close()
+}
+
+func (c *trackedConn) CloseWrite() error {
+	return closeWrite(c.Conn)
+}
+
+func closeWrite(conn net.Conn) error {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Policy
+		wantErr bool
+	}{
+		{value: "", want: PolicyRoundRobin},
+		{value: "round-robin", want: PolicyRoundRobin},
+		{value: "least-connections", want: PolicyLeastConnections},
+		{value: "Round-Robin", wantErr: true},
+		{value: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func listenBackend(t *testing.T) (net.Listener, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return listener, listener.Addr().String()
+}
+
+func closedAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func dialBackend(t *testing.T, pool *Pool) (net.Conn, *Backend) {
+	t.Helper()
+
+	conn, err := pool.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, conn.(*trackedConn).backend
+}
+
+func TestRoundRobinAlternatesBackends(t *testing.T) {
+	_, addrA := listenBackend(t)
+	_, addrB := listenBackend(t)
+	a := &Backend{Name: "a", Addr: addrA}
+	b := &Backend{Name: "b", Addr: addrB}
+	pool := NewPool(PolicyRoundRobin, []*Backend{a, b}, nil)
+	defer pool.Close()
+
+	var picked []string
+	for range 4 {
+		conn, backend := dialBackend(t, pool)
+		conn.Close()
+		picked = append(picked, backend.Name)
+	}
+
+	want := []string{"a", "b", "a", "b"}
+	for i := range want {
+		if picked[i] != want[i] {
+			t.Fatalf("picked %v, want %v", picked, want)
+		}
+	}
+}
+
+func TestLeastConnectionsPrefersIdleBackend(t *testing.T) {
+	_, addrA := listenBackend(t)
+	_, addrB := listenBackend(t)
+	a := &Backend{Name: "a", Addr: addrA}
+	b := &Backend{Name: "b", Addr: addrB}
+	pool := NewPool(PolicyLeastConnections, []*Backend{a, b}, nil)
+	defer pool.Close()
+
+	held, first := dialBackend(t, pool)
+	defer held.Close()
+
+	for range 3 {
+		conn, backend := dialBackend(t, pool)
+		conn.Close()
+		if backend == first {
+			t.Fatalf("picked busy backend %s while another was idle", backend.Name)
+		}
+	}
+
+	held.Close()
+	if first.active.Load() != 0 {
+		t.Errorf("expected closing the connection to release it, active = %d", first.active.Load())
+	}
+}
+
+func TestDialSkipsRefusingBackend(t *testing.T) {
+	_, addr := listenBackend(t)
+	down := &Backend{Name: "down", Addr: closedAddr(t)}
+	up := &Backend{Name: "up", Addr: addr}
+	pool := NewPool(PolicyRoundRobin, []*Backend{down, up}, nil)
+	defer pool.Close()
+
+	for range 3 {
+		conn, backend := dialBackend(t, pool)
+		conn.Close()
+		if backend != up {
+			t.Fatalf("picked %s, want up", backend.Name)
+		}
+	}
+
+	pool.mu.Lock()
+	unhealthy := down.unhealthy
+	pool.mu.Unlock()
+	if !unhealthy {
+		t.Error("expected the refusing backend to be marked unhealthy")
+	}
+}
+
+func TestDialFailsWhenEveryBackendRefuses(t *testing.T) {
+	pool := NewPool(PolicyRoundRobin, []*Backend{
+		{Name: "a", Addr: closedAddr(t)},
+		{Name: "b", Addr: closedAddr(t)},
+	}, nil)
+	defer pool.Close()
+
+	for range 2 {
+		if _, err := pool.Dial(context.Background()); !errors.Is(err, ErrNoBackends) {
+			t.Fatalf("Dial() error = %v, want %v", err, ErrNoBackends)
+		}
+	}
+
+	if _, err := NewPool(PolicyRoundRobin, nil, nil).Dial(context.Background()); !errors.Is(err, ErrNoBackends) {
+		t.Errorf("Dial() on an empty pool error = %v, want %v", err, ErrNoBackends)
+	}
+}
+
+func TestProbeRestoresRecoveredBackend(t *testing.T) {
+	addr := closedAddr(t)
+	backend := &Backend{Name: "a", Addr: addr}
+	pool := NewPool(PolicyRoundRobin, []*Backend{backend}, nil)
+	defer pool.Close()
+
+	if _, err := pool.Dial(context.Background()); !errors.Is(err, ErrNoBackends) {
+		t.Fatalf("Dial() error = %v, want %v", err, ErrNoBackends)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("could not listen on %s again: %v", addr, err)
+	}
+	defer listener.Close()
+
+	deadline := time.Now().Add(probeBackoff + 2*time.Second)
+	for time.Now().Before(deadline) {
+		pool.mu.Lock()
+		unhealthy := backend.unhealthy
+		pool.mu.Unlock()
+		if !unhealthy {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("expected the probe to mark the backend healthy once it accepts connections")
+}
+
+func TestRemoveCallsOnRemove(t *testing.T) {
+	a := &Backend{Name: "a"}
+	b := &Backend{Name: "b"}
+	var removed []*Backend
+	pool := NewPool(PolicyRoundRobin, []*Backend{a, b}, func(backend *Backend) {
+		removed = append(removed, backend)
+	})
+
+	pool.Remove(a)
+	pool.Remove(a)
+
+	if len(removed) != 1 || removed[0] != a {
+		t.Errorf("onRemove called with %v, want only a", removed)
+	}
+	if backends := pool.Backends(); len(backends) != 1 || backends[0] != b {
+		t.Errorf("remaining backends %v, want only b", backends)
+	}
+}
@@ -0,0 +1,251 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour
+)
+
+type AuthorityInterface interface {
+	CertificatePEM() []byte
+	CertificatePath() string
+	IssueCertificate(host string) (*tls.Certificate, error)
+}
+
+type authority struct {
+	dir     string
+	cert    *x509.Certificate
+	key     crypto.Signer
+	certPEM []byte
+	leaves  map[string]*tls.Certificate
+	mu      sync.Mutex
+}
+
+func NewAuthority(dir string) (AuthorityInterface, error) {
+	a := &authority{
+		dir:    dir,
+		leaves: make(map[string]*tls.Certificate),
+	}
+
+	certExists, err := fileExists(filepath.Join(dir, caCertFile))
+	if err != nil {
+		return nil, err
+	}
+	keyExists, err := fileExists(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case certExists && keyExists:
+		if err := a.load(); err != nil {
+			return nil, err
+		}
+	case !certExists && !keyExists:
+		if err := a.generate(); err != nil {
+			return nil, err
+		}
+	case certExists:
+		return nil, fmt.Errorf("CA key %s is missing; restore it or remove %s to create a new CA", filepath.Join(dir, caKeyFile), filepath.Join(dir, caCertFile))
+	default:
+		return nil, fmt.Errorf("CA certificate %s is missing; restore it or remove %s to create a new CA", filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile))
+	}
+	return a, nil
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("stat %s: %w", path, err)
+}
+
+func (a *authority) CertificatePEM() []byte {
+	return a.certPEM
+}
+
+func (a *authority) CertificatePath() string {
+	return filepath.Join(a.dir, caCertFile)
+}
+
+func (a *authority) IssueCertificate(host string) (*tls.Certificate, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if leaf, ok := a.leaves[host]; ok && time.Now().Before(leaf.Leaf.NotAfter) {
+		return leaf, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key for %s: %w", host, err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate for %s: %w", host, err)
+	}
+
+	leafCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate for %s: %w", host, err)
+	}
+
+	leaf := &tls.Certificate{
+		Certificate: [][]byte{der, a.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leafCert,
+	}
+	a.leaves[host] = leaf
+	return leaf, nil
+}
+
+func (a *authority) load() error {
+	certPEM, err := os.ReadFile(filepath.Join(a.dir, caCertFile))
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(a.dir, caKeyFile))
+	if err != nil {
+		return err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return fmt.Errorf("decode CA certificate: no PEM data")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return fmt.Errorf("decode CA key: no PEM data")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("parse CA key: unsupported key type %T", key)
+	}
+
+	a.cert = cert
+	a.key = signer
+	a.certPEM = certPEM
+	return nil
+}
+
+func (a *authority) generate() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate CA key: %w", err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   fmt.Sprintf("kube-service-tunnel local CA (%s)", hostname),
+			Organization: []string{"kube-service-tunnel"},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("create CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("parse CA certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("marshal CA key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if err := config.WriteFile(filepath.Join(a.dir, caKeyFile), keyPEM, 0600); err != nil {
+		return fmt.Errorf("save CA key: %w", err)
+	}
+	if err := config.WriteFile(filepath.Join(a.dir, caCertFile), certPEM, 0644); err != nil {
+		return fmt.Errorf("save CA certificate: %w", err)
+	}
+
+	a.cert = cert
+	a.key = key
+	a.certPEM = certPEM
+	return nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
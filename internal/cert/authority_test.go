@@ -0,0 +1,115 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAuthorityCreatesCA(t *testing.T) {
+	dir := t.TempDir()
+
+	a, err := NewAuthority(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{caCertFile, caKeyFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, caKeyFile)); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("expected CA key mode 0600, got %v", info.Mode().Perm())
+	}
+	if a.CertificatePath() != filepath.Join(dir, caCertFile) {
+		t.Errorf("unexpected certificate path %s", a.CertificatePath())
+	}
+}
+
+func TestNewAuthorityLoadsExistingCA(t *testing.T) {
+	dir := t.TempDir()
+
+	created, err := NewAuthority(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := NewAuthority(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(created.CertificatePEM(), loaded.CertificatePEM()) {
+		t.Fatal("expected the existing CA to be loaded instead of a new one")
+	}
+
+	leaf, err := loaded.IssueCertificate("api.default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(created.CertificatePEM())
+	if _, err := leaf.Leaf.Verify(x509.VerifyOptions{DNSName: "api.default", Roots: pool}); err != nil {
+		t.Errorf("leaf issued by the loaded CA does not verify against the original: %v", err)
+	}
+}
+
+func TestNewAuthorityRejectsPartialCA(t *testing.T) {
+	tests := []struct {
+		name    string
+		remove  string
+		message string
+	}{
+		{name: "missing key", remove: caKeyFile, message: "CA key"},
+		{name: "missing certificate", remove: caCertFile, message: "CA certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := NewAuthority(dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(dir, tt.remove)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := NewAuthority(dir)
+			if err == nil {
+				t.Fatal("expected an error for a partial CA")
+			}
+			if !strings.Contains(err.Error(), tt.message+" "+filepath.Join(dir, tt.remove)+" is missing") {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, tt.remove)); !os.IsNotExist(err) {
+				t.Errorf("expected %s not to be recreated", tt.remove)
+			}
+		})
+	}
+}
+
+func TestIssueCertificateUsesIPSANForAddresses(t *testing.T) {
+	a, err := NewAuthority(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := a.IssueCertificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaf.Leaf.IPAddresses) != 1 || len(leaf.Leaf.DNSNames) != 0 {
+		t.Errorf("expected a single IP SAN, got IPs %v and names %v", leaf.Leaf.IPAddresses, leaf.Leaf.DNSNames)
+	}
+
+	again, err := a.IssueCertificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != leaf {
+		t.Error("expected the cached leaf certificate to be reused")
+	}
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/balancer"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	appName        = "kube-service-tunnel"
+	configFileName = "config.yaml"
+)
+
+type Config struct {
+	Inspector     InspectorConfig          `json:"inspector"`
+	HAR           HARConfig                `json:"har"`
+	Routes        []RouteRule              `json:"routes,omitempty"`
+	VirtualHosts  []VirtualHost            `json:"virtualHosts,omitempty"`
+	Lazy          LazyConfig               `json:"lazy"`
+	LoadBalancing LoadBalancingConfig      `json:"loadBalancing"`
+	Services      ServicesConfig           `json:"services"`
+	Relay         RelayConfig              `json:"relay"`
+	Contexts      map[string]ContextConfig `json:"contexts,omitempty"`
+	Ports         PortsConfig              `json:"ports"`
+}
+
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+type LazyConfig struct {
+	Enabled     bool     `json:"enabled,omitempty"`
+	IdleTimeout Duration `json:"idleTimeout,omitempty"`
+}
+
+type LoadBalancingConfig struct {
+	Replicas int    `json:"replicas,omitempty"`
+	Policy   string `json:"policy,omitempty"`
+}
+
+type ServicesConfig struct {
+	ExcludeTypes []string `json:"excludeTypes,omitempty"`
+}
+
+type RelayConfig struct {
+	Enabled   bool              `json:"enabled,omitempty"`
+	Image     string            `json:"image,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	CPU       string            `json:"cpu,omitempty"`
+	Memory    string            `json:"memory,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type PortsConfig struct {
+	Min int32 `json:"min,omitempty"`
+	Max int32 `json:"max,omitempty"`
+}
+
+type ContextConfig struct {
+	Transport string `json:"transport,omitempty"`
+}
+
+const (
+	TransportPortForward = "port-forward"
+	TransportAPIServer   = "api-server"
+)
+
+var Transports = []string{TransportPortForward, TransportAPIServer}
+
+var serviceTypes = []string{"ClusterIP", "NodePort", "LoadBalancer", "ExternalName"}
+
+type InspectorConfig struct {
+	Capacity       int   `json:"capacity,omitempty"`
+	CaptureHeaders bool  `json:"captureHeaders,omitempty"`
+	MaxBodyBytes   int64 `json:"maxBodyBytes,omitempty"`
+}
+
+type HARConfig struct {
+	Dir           string   `json:"dir,omitempty"`
+	CaptureHosts  []string `json:"captureHosts,omitempty"`
+	MaxBodyBytes  int64    `json:"maxBodyBytes,omitempty"`
+	MaxEntries    int      `json:"maxEntries,omitempty"`
+	RedactHeaders []string `json:"redactHeaders,omitempty"`
+}
+
+type HeaderRules struct {
+	Add    map[string]string `json:"add,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+type RouteRule struct {
+	Host            string      `json:"host"`
+	RequestHeaders  HeaderRules `json:"requestHeaders,omitempty"`
+	ResponseHeaders HeaderRules `json:"responseHeaders,omitempty"`
+	HostRewrite     string      `json:"hostRewrite,omitempty"`
+	StripPrefix     string      `json:"stripPrefix,omitempty"`
+	AddPrefix       string      `json:"addPrefix,omitempty"`
+	CORS            bool        `json:"cors,omitempty"`
+}
+
+type VirtualHostPath struct {
+	Prefix string `json:"prefix"`
+	Tunnel string `json:"tunnel"`
+}
+
+type VirtualHost struct {
+	Host  string            `json:"host"`
+	Paths []VirtualHostPath `json:"paths"`
+}
+
+func Default() *Config {
+	return &Config{
+		Inspector: InspectorConfig{
+			Capacity: 200,
+		},
+		HAR: HARConfig{
+			MaxBodyBytes:  1 << 20,
+			MaxEntries:    10000,
+			RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		},
+		Lazy: LazyConfig{
+			IdleTimeout: Duration(5 * time.Minute),
+		},
+		LoadBalancing: LoadBalancingConfig{
+			Replicas: 1,
+			Policy:   string(balancer.PolicyRoundRobin),
+		},
+		Relay: RelayConfig{
+			Image:  "alpine/socat:latest",
+			CPU:    "100m",
+			Memory: "64Mi",
+		},
+		Ports: PortsConfig{
+			Min: 40000,
+			Max: 65534,
+		},
+	}
+}
+
+func Dir() (string, error) {
+	base, err := userConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("get user config directory: %w", err)
+	}
+
+	dir := filepath.Join(base, appName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create config directory %s: %w", dir, err)
+	}
+	chownToSudoUser(dir)
+
+	return dir, nil
+}
+
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+func Load(path string, required bool) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+	for i, rule := range c.Routes {
+		if rule.Host == "" {
+			return fmt.Errorf("routes[%d]: host is required", i)
+		}
+		if rule.StripPrefix != "" && !strings.HasPrefix(rule.StripPrefix, "/") {
+			return fmt.Errorf("routes[%d]: stripPrefix must start with /", i)
+		}
+		if rule.AddPrefix != "" && !strings.HasPrefix(rule.AddPrefix, "/") {
+			return fmt.Errorf("routes[%d]: addPrefix must start with /", i)
+		}
+	}
+	if c.Lazy.IdleTimeout < 0 {
+		return fmt.Errorf("lazy.idleTimeout must not be negative")
+	}
+	if c.LoadBalancing.Replicas < 0 {
+		return fmt.Errorf("loadBalancing.replicas must not be negative")
+	}
+	if _, err := balancer.ParsePolicy(c.LoadBalancing.Policy); err != nil {
+		return fmt.Errorf("loadBalancing.policy: %w", err)
+	}
+	for i, serviceType := range c.Services.ExcludeTypes {
+		if !isServiceType(serviceType) {
+			return fmt.Errorf("services.excludeTypes[%d]: unknown service type %s (expected one of %s)", i, serviceType, strings.Join(serviceTypes, ", "))
+		}
+	}
+	if c.Ports.Min < 1 || c.Ports.Max > 65535 || c.Ports.Min > c.Ports.Max {
+		return fmt.Errorf("ports: range %d-%d must lie within 1-65535 with min not above max", c.Ports.Min, c.Ports.Max)
+	}
+	for name, context := range c.Contexts {
+		if context.Transport != "" && !isTransport(context.Transport) {
+			return fmt.Errorf("contexts.%s.transport: unknown transport %s (expected one of %s)", name, context.Transport, strings.Join(Transports, ", "))
+		}
+	}
+	for i, vh := range c.VirtualHosts {
+		if vh.Host == "" {
+			return fmt.Errorf("virtualHosts[%d]: host is required", i)
+		}
+		if len(vh.Paths) == 0 {
+			return fmt.Errorf("virtualHosts[%d]: at least one path is required", i)
+		}
+		for j, path := range vh.Paths {
+			if path.Tunnel == "" {
+				return fmt.Errorf("virtualHosts[%d].paths[%d]: tunnel is required", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+func isServiceType(value string) bool {
+	for _, serviceType := range serviceTypes {
+		if strings.EqualFold(serviceType, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTransport(value string) bool {
+	for _, transport := range Transports {
+		if transport == value {
+			return true
+		}
+	}
+	return false
+}
+
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	chownToSudoUser(path)
+	return nil
+}
+
+func userConfigDir() (string, error) {
+	sudoUser := os.Getenv("SUDO_USER")
+	if sudoUser == "" || os.Geteuid() != 0 {
+		return os.UserConfigDir()
+	}
+
+	u, err := user.Lookup(sudoUser)
+	if err != nil {
+		return os.UserConfigDir()
+	}
+
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(u.HomeDir, "Library", "Application Support"), nil
+	}
+	return filepath.Join(u.HomeDir, ".config"), nil
+}
+
+func chownToSudoUser(path string) {
+	if os.Geteuid() != 0 {
+		return
+	}
+
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil {
+		return
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		return
+	}
+
+	_ = os.Chown(path, uid, gid)
+}
@@ -11,6 +11,7 @@ import (
 
 type HostsFileAdapterInterface interface {
 	AddEntry(dnsURL string) error
+	AddEntryWithIP(dnsURL, ip string) error
 	RemoveEntry(dnsURL string) error
 	ClearAllEntries() error
 }
@@ -29,11 +30,14 @@ func NewHostsFileAdapter() *hostsFileAdapter {
 }
 
 func (h *hostsFileAdapter) AddEntry(dnsURL string) error {
+	return h.AddEntryWithIP(dnsURL, "127.0.0.1")
+}
+
+func (h *hostsFileAdapter) AddEntryWithIP(dnsURL, ip string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	const hostsPath = "/etc/hosts"
-	const ip = "127.0.0.1"
 
 	lines, err := h.readHostsFile(hostsPath)
 	if err != nil {
@@ -55,7 +59,7 @@ func (h *hostsFileAdapter) AddEntry(dnsURL string) error {
 			continue
 		}
 		if trimmedLine == h.endMarker {
-			sectionEndIndex = i
+			sectionEndIndex = len(newLines)
 			inTunnelSection = false
 			newLines = append(newLines, line)
 			continue
@@ -63,6 +67,9 @@ func (h *hostsFileAdapter) AddEntry(dnsURL string) error {
 		if inTunnelSection {
 			parts := strings.Fields(trimmedLine)
 			if len(parts) >= 2 && parts[1] == dnsURL {
+				if parts[0] != ip {
+					continue
+				}
 				entryExists = true
 			}
 			newLines = append(newLines, line)
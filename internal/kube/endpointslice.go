@@ -0,0 +1,238 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type EndpointPort struct {
+	Name     string
+	Port     int32
+	Protocol string
+}
+
+type Endpoint struct {
+	Pod   string
+	Ready bool
+	Ports []EndpointPort
+}
+
+type EndpointSliceInterface interface {
+	ListServiceEndpoints(ctx context.Context, namespace, contextName, serviceName string) ([]Endpoint, bool, error)
+}
+
+type endpointSliceClient struct {
+	kubeconfigPath string
+}
+
+func NewEndpointSliceClient(kubeconfigPath string) (*endpointSliceClient, error) {
+	return &endpointSliceClient{
+		kubeconfigPath: kubeconfigPath,
+	}, nil
+}
+
+func (e *endpointSliceClient) ListServiceEndpoints(ctx context.Context, namespace, contextName, serviceName string) ([]Endpoint, bool, error) {
+	config, err := loadKubeconfigWithContext(e.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, false, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, false, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	slices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + serviceName,
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("list endpointslices for service %s/%s: %w", namespace, serviceName, err)
+	}
+
+	if len(slices.Items) == 0 {
+		return nil, false, nil
+	}
+
+	var result []Endpoint
+	for _, slice := range slices.Items {
+		result = append(result, convertEndpointSlice(slice)...)
+	}
+
+	return result, true, nil
+}
+
+func convertEndpointSlice(slice discoveryv1.EndpointSlice) []Endpoint {
+	var ports []EndpointPort
+	for _, port := range slice.Ports {
+		if port.Port == nil {
+			continue
+		}
+		endpointPort := EndpointPort{Port: *port.Port}
+		if port.Name != nil {
+			endpointPort.Name = *port.Name
+		}
+		if port.Protocol != nil {
+			endpointPort.Protocol = string(*port.Protocol)
+		}
+		ports = append(ports, endpointPort)
+	}
+
+	var result []Endpoint
+	for _, endpoint := range slice.Endpoints {
+		if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+			continue
+		}
+		result = append(result, Endpoint{
+			Pod:   endpoint.TargetRef.Name,
+			Ready: endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready,
+			Ports: ports,
+		})
+	}
+	return result
+}
+
+type ServiceBackend struct {
+	Pod  string
+	Port int32
+}
+
+func ResolveServiceBackends(
+	ctx context.Context,
+	endpointClient EndpointSliceInterface,
+	podClient PodInterface,
+	contextName string,
+	svc *Service,
+	httpPort *ServicePort,
+	limit int,
+) ([]ServiceBackend, error) {
+	endpoints, found, err := endpointClient.ListServiceEndpoints(ctx, svc.Namespace, contextName, svc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		var backends []ServiceBackend
+		seen := make(map[string]bool)
+		for _, endpoint := range endpoints {
+			if limit > 0 && len(backends) == limit {
+				break
+			}
+			if !endpoint.Ready || seen[endpoint.Pod] {
+				continue
+			}
+			port := endpointPortFor(endpoint.Ports, httpPort)
+			if port == 0 {
+				continue
+			}
+			seen[endpoint.Pod] = true
+			backends = append(backends, ServiceBackend{Pod: endpoint.Pod, Port: port})
+		}
+		if len(backends) == 0 {
+			return nil, fmt.Errorf("no ready pod endpoints for service %s/%s port %d", svc.Namespace, svc.Name, httpPort.Port)
+		}
+		return backends, nil
+	}
+
+	if len(svc.Selector) == 0 {
+		return nil, fmt.Errorf("service %s/%s has no selector and no endpoints", svc.Namespace, svc.Name)
+	}
+
+	if limit == 1 {
+		pod, podPort, err := FindPodAndPortForService(ctx, podClient, svc.Namespace, contextName, svc.Selector, httpPort)
+		if err != nil {
+			return nil, err
+		}
+		return []ServiceBackend{{Pod: pod.Name, Port: podPort}}, nil
+	}
+
+	pods, podPorts, err := FindPodsAndPortsForService(ctx, podClient, svc.Namespace, contextName, svc.Selector, httpPort, limit)
+	if err != nil {
+		return nil, err
+	}
+	backends := make([]ServiceBackend, 0, len(pods))
+	for i, pod := range pods {
+		backends = append(backends, ServiceBackend{Pod: pod.Name, Port: podPorts[i]})
+	}
+	return backends, nil
+}
+
+func endpointPortFor(ports []EndpointPort, httpPort *ServicePort) int32 {
+	for _, port := range ports {
+		if port.Name == httpPort.Name {
+			return port.Port
+		}
+	}
+	if len(ports) == 1 {
+		return ports[0].Port
+	}
+	return 0
+}
+
+func FindServicePods(
+	ctx context.Context,
+	endpointClient EndpointSliceInterface,
+	podClient PodInterface,
+	contextName string,
+	svc *Service,
+) ([]Pod, error) {
+	endpoints, found, err := endpointClient.ListServiceEndpoints(ctx, svc.Namespace, contextName, svc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		if len(svc.Selector) == 0 {
+			return nil, fmt.Errorf("service %s/%s has no selector and no endpoints", svc.Namespace, svc.Name)
+		}
+		return podClient.FindMatchingPods(ctx, svc.Namespace, contextName, svc.Selector)
+	}
+
+	names := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		names[endpoint.Pod] = true
+	}
+
+	pods, err := podClient.ListPods(ctx, svc.Namespace, contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Pod
+	for _, pod := range pods {
+		if names[pod.Name] {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+func resolveBackendPort(ctx context.Context, endpointClient EndpointSliceInterface, contextName string, svc *Service, httpPort *ServicePort, pod Pod) (int32, error) {
+	endpoints, found, err := endpointClient.ListServiceEndpoints(ctx, svc.Namespace, contextName, svc.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	if found {
+		for _, endpoint := range endpoints {
+			if endpoint.Pod != pod.Name {
+				continue
+			}
+			if port := endpointPortFor(endpoint.Ports, httpPort); port != 0 {
+				return port, nil
+			}
+		}
+	}
+
+	if port := resolvePodPort(pod, httpPort); port != 0 {
+		return port, nil
+	}
+	return 0, fmt.Errorf("could not determine port of pod %s for service port %d", pod.Name, httpPort.Port)
+}
@@ -0,0 +1,150 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+type fakeEndpointClient struct {
+	endpoints []Endpoint
+	found     bool
+}
+
+func (f *fakeEndpointClient) ListServiceEndpoints(ctx context.Context, namespace, contextName, serviceName string) ([]Endpoint, bool, error) {
+	return f.endpoints, f.found, nil
+}
+
+type fakePodClient struct {
+	pods []Pod
+}
+
+func (f *fakePodClient) ListPods(ctx context.Context, namespace, contextName string) ([]Pod, error) {
+	return f.pods, nil
+}
+
+func (f *fakePodClient) FindMatchingPods(ctx context.Context, namespace, contextName string, selector map[string]string) ([]Pod, error) {
+	return f.pods, nil
+}
+
+func (f *fakePodClient) FindSelectedPods(ctx context.Context, namespace, contextName, selector string) ([]Pod, error) {
+	return f.pods, nil
+}
+
+func (f *fakePodClient) GetPod(ctx context.Context, namespace, contextName, name string) (Pod, error) {
+	for _, pod := range f.pods {
+		if pod.Name == name {
+			return pod, nil
+		}
+	}
+	return Pod{}, fmt.Errorf("pod %s not found", name)
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestConvertEndpointSlice(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	slice := discoveryv1.EndpointSlice{
+		Ports: []discoveryv1.EndpointPort{
+			{Name: ptr("http"), Port: ptr(int32(8080)), Protocol: &tcp},
+			{Name: ptr("unset")},
+		},
+		Endpoints: []discoveryv1.Endpoint{
+			{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "ready"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr(true)}},
+			{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "unknown"}},
+			{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "terminating"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr(false)}},
+			{TargetRef: &corev1.ObjectReference{Kind: "Node", Name: "node"}},
+			{},
+		},
+	}
+
+	ports := []EndpointPort{{Name: "http", Port: 8080, Protocol: "TCP"}}
+	want := []Endpoint{
+		{Pod: "ready", Ready: true, Ports: ports},
+		{Pod: "unknown", Ready: true, Ports: ports},
+		{Pod: "terminating", Ready: false, Ports: ports},
+	}
+	if got := convertEndpointSlice(slice); !reflect.DeepEqual(got, want) {
+		t.Errorf("convertEndpointSlice() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveServiceBackendsUsesReadyEndpoints(t *testing.T) {
+	ports := []EndpointPort{{Name: "metrics", Port: 9090}, {Name: "http", Port: 8080}}
+	endpoints := &fakeEndpointClient{found: true, endpoints: []Endpoint{
+		{Pod: "a", Ready: false, Ports: ports},
+		{Pod: "b", Ready: true, Ports: ports},
+		{Pod: "b", Ready: true, Ports: ports},
+		{Pod: "c", Ready: true, Ports: ports},
+		{Pod: "d", Ready: true, Ports: ports},
+	}}
+	svc := &Service{Name: "api", Namespace: "default", Selector: map[string]string{"app": "api"}}
+	httpPort := &ServicePort{Name: "http", Port: 80}
+
+	backends, err := ResolveServiceBackends(context.Background(), endpoints, &fakePodClient{}, "ctx", svc, httpPort, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ServiceBackend{{Pod: "b", Port: 8080}, {Pod: "c", Port: 8080}}
+	if !reflect.DeepEqual(backends, want) {
+		t.Errorf("ResolveServiceBackends() = %+v, want %+v", backends, want)
+	}
+}
+
+func TestResolveServiceBackendsFailsWithoutReadyEndpoints(t *testing.T) {
+	endpoints := &fakeEndpointClient{found: true, endpoints: []Endpoint{
+		{Pod: "a", Ready: false, Ports: []EndpointPort{{Name: "http", Port: 8080}}},
+		{Pod: "b", Ready: true, Ports: []EndpointPort{{Name: "grpc", Port: 9000}, {Name: "admin", Port: 9001}}},
+	}}
+	pods := &fakePodClient{pods: []Pod{{Name: "a", Ready: true, Ports: []PodPort{{Name: "http", ContainerPort: 8080}}}}}
+	svc := &Service{Name: "api", Namespace: "default", Selector: map[string]string{"app": "api"}}
+
+	_, err := ResolveServiceBackends(context.Background(), endpoints, pods, "ctx", svc, &ServicePort{Name: "http", Port: 80}, 0)
+	if err == nil || !strings.Contains(err.Error(), "no ready pod endpoints") {
+		t.Errorf("expected no ready pod endpoints error, got %v", err)
+	}
+}
+
+func TestResolveServiceBackendsFallsBackToSelector(t *testing.T) {
+	pods := &fakePodClient{pods: []Pod{
+		{Name: "a", Ready: false, Ports: []PodPort{{Name: "http", ContainerPort: 8080}}},
+		{Name: "b", Ready: true, Ports: []PodPort{{Name: "http", ContainerPort: 8080}}},
+	}}
+	svc := &Service{Name: "api", Namespace: "default", Selector: map[string]string{"app": "api"}}
+	httpPort := &ServicePort{Name: "http", Port: 80}
+
+	backends, err := ResolveServiceBackends(context.Background(), &fakeEndpointClient{}, pods, "ctx", svc, httpPort, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ServiceBackend{{Pod: "b", Port: 8080}}; !reflect.DeepEqual(backends, want) {
+		t.Errorf("ResolveServiceBackends() = %+v, want %+v", backends, want)
+	}
+
+	svc.Selector = nil
+	if _, err := ResolveServiceBackends(context.Background(), &fakeEndpointClient{}, pods, "ctx", svc, httpPort, 0); err == nil {
+		t.Error("expected an error for a service without selector or endpoints")
+	}
+}
+
+func TestEndpointPortFor(t *testing.T) {
+	httpPort := &ServicePort{Name: "http", Port: 80}
+
+	if got := endpointPortFor([]EndpointPort{{Name: "admin", Port: 9000}, {Name: "http", Port: 8080}}, httpPort); got != 8080 {
+		t.Errorf("named port = %d, want 8080", got)
+	}
+	if got := endpointPortFor([]EndpointPort{{Port: 3000}}, httpPort); got != 3000 {
+		t.Errorf("single unnamed port = %d, want 3000", got)
+	}
+	if got := endpointPortFor([]EndpointPort{{Name: "a", Port: 1}, {Name: "b", Port: 2}}, httpPort); got != 0 {
+		t.Errorf("ambiguous ports = %d, want 0", got)
+	}
+}
@@ -0,0 +1,93 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+func (m *kubeAdapter) SyncHeadlessForwards(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, current []PodForward, usedPorts map[int32]bool) ([]PodForward, []PodForward, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	services, err := m.ListServices(ctx, namespace, contextName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list services: %w", err)
+	}
+
+	var targetService *Service
+	for _, svc := range services {
+		if svc.Name == serviceName {
+			targetService = &svc
+			break
+		}
+	}
+	if targetService == nil {
+		return nil, nil, fmt.Errorf("service %s/%s not found", namespace, serviceName)
+	}
+
+	httpPort := findServicePort(targetService, servicePort)
+	if httpPort == nil {
+		return nil, nil, fmt.Errorf("port %d not found for service %s/%s", servicePort, namespace, serviceName)
+	}
+
+	backends, err := ResolveServiceBackends(ctx, m.endpointClient, m.podClient, contextName, targetService, httpPort, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desired := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		desired[backend.Pod] = true
+	}
+
+	existing := make(map[string]bool, len(current))
+	var removed []PodForward
+	for _, forward := range current {
+		existing[forward.Pod] = true
+		if !desired[forward.Pod] {
+			removed = append(removed, forward)
+		}
+	}
+
+	var missing []ServiceBackend
+	for _, backend := range backends {
+		if !existing[backend.Pod] {
+			missing = append(missing, backend)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, removed, nil
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, removed, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, removed, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	tunnel := ServiceTunnel{
+		Context:     contextName,
+		Namespace:   namespace,
+		ServiceName: serviceName,
+		ServicePort: servicePort,
+		Headless:    true,
+	}
+
+	var added []PodForward
+	for _, backend := range missing {
+		forward, err := m.startPodForward(ctx, tunnel, backend, buildBackendPortKey(tunnel, backend), usedPorts, config, clientset)
+		if err != nil {
+			continue
+		}
+		added = append(added, forward)
+	}
+
+	return added, removed, nil
+}
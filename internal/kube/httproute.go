@@ -0,0 +1,187 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var httpRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+type HTTPRouteHeaderMatch struct {
+	Name  string
+	Value string
+	Regex bool
+}
+
+type HTTPRouteMatch struct {
+	PathType    string
+	Path        string
+	Headers     []HTTPRouteHeaderMatch
+	Method      string
+	QueryParams []string
+}
+
+type HTTPRouteBackend struct {
+	ServiceName string
+	Namespace   string
+	Port        int32
+	Weight      int32
+}
+
+type HTTPRouteRule struct {
+	Matches  []HTTPRouteMatch
+	Backends []HTTPRouteBackend
+}
+
+type HTTPRoute struct {
+	Name      string
+	Namespace string
+	Hostnames []string
+	Rules     []HTTPRouteRule
+}
+
+type HTTPRouteInterface interface {
+	ListHTTPRoutes(ctx context.Context, namespace, contextName string) ([]HTTPRoute, error)
+}
+
+type httpRouteClient struct {
+	kubeconfigPath string
+}
+
+func NewHTTPRouteClient(kubeconfigPath string) (*httpRouteClient, error) {
+	return &httpRouteClient{
+		kubeconfigPath: kubeconfigPath,
+	}, nil
+}
+
+type httpRouteSpec struct {
+	Hostnames []string `json:"hostnames"`
+	Rules     []struct {
+		Matches []struct {
+			Path *struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"path"`
+			Headers []struct {
+				Type  string `json:"type"`
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"headers"`
+			QueryParams []struct {
+				Name string `json:"name"`
+			} `json:"queryParams"`
+			Method string `json:"method"`
+		} `json:"matches"`
+		BackendRefs []struct {
+			Group     *string `json:"group"`
+			Kind      *string `json:"kind"`
+			Name      string  `json:"name"`
+			Namespace *string `json:"namespace"`
+			Port      *int32  `json:"port"`
+			Weight    *int32  `json:"weight"`
+		} `json:"backendRefs"`
+	} `json:"rules"`
+}
+
+func (c *httpRouteClient) ListHTTPRoutes(ctx context.Context, namespace, contextName string) ([]HTTPRoute, error) {
+	config, err := loadKubeconfigWithContext(c.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create dynamic client: %w", err)
+	}
+
+	list, err := client.Resource(httpRouteResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("HTTPRoute resources are not available in context %s (Gateway API CRDs not installed)", contextName)
+		}
+		return nil, fmt.Errorf("list httproutes in namespace %s: %w", namespace, err)
+	}
+
+	var result []HTTPRoute
+	for _, item := range list.Items {
+		specMap, found, err := unstructured.NestedMap(item.Object, "spec")
+		if err != nil || !found {
+			continue
+		}
+		var spec httpRouteSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, &spec); err != nil {
+			continue
+		}
+
+		route := HTTPRoute{
+			Name:      item.GetName(),
+			Namespace: item.GetNamespace(),
+			Hostnames: spec.Hostnames,
+		}
+
+		for _, rule := range spec.Rules {
+			var routeRule HTTPRouteRule
+			for _, match := range rule.Matches {
+				routeMatch := HTTPRouteMatch{PathType: "PathPrefix", Path: "/", Method: match.Method}
+				if match.Path != nil {
+					if match.Path.Type != "" {
+						routeMatch.PathType = match.Path.Type
+					}
+					if match.Path.Value != "" {
+						routeMatch.Path = match.Path.Value
+					}
+				}
+				for _, header := range match.Headers {
+					routeMatch.Headers = append(routeMatch.Headers, HTTPRouteHeaderMatch{
+						Name:  header.Name,
+						Value: header.Value,
+						Regex: header.Type == "RegularExpression",
+					})
+				}
+				for _, param := range match.QueryParams {
+					routeMatch.QueryParams = append(routeMatch.QueryParams, param.Name)
+				}
+				routeRule.Matches = append(routeRule.Matches, routeMatch)
+			}
+			if len(routeRule.Matches) == 0 {
+				routeRule.Matches = []HTTPRouteMatch{{PathType: "PathPrefix", Path: "/"}}
+			}
+
+			for _, ref := range rule.BackendRefs {
+				if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != "Service") || ref.Port == nil {
+					continue
+				}
+				backend := HTTPRouteBackend{
+					ServiceName: ref.Name,
+					Namespace:   item.GetNamespace(),
+					Port:        *ref.Port,
+					Weight:      1,
+				}
+				if ref.Namespace != nil && *ref.Namespace != "" {
+					backend.Namespace = *ref.Namespace
+				}
+				if ref.Weight != nil {
+					backend.Weight = *ref.Weight
+				}
+				routeRule.Backends = append(routeRule.Backends, backend)
+			}
+
+			route.Rules = append(route.Rules, routeRule)
+		}
+
+		result = append(result, route)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type IngressPath struct {
+	Path        string
+	PathType    string
+	ServiceName string
+	ServicePort int32
+}
+
+type IngressRule struct {
+	Host  string
+	Paths []IngressPath
+}
+
+type Ingress struct {
+	Name      string
+	Namespace string
+	ClassName string
+	Rules     []IngressRule
+}
+
+func (i Ingress) Hosts() []string {
+	var hosts []string
+	for _, rule := range i.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+type IngressInterface interface {
+	ListIngresses(ctx context.Context, namespace, contextName string) ([]Ingress, error)
+}
+
+type ingressClient struct {
+	kubeconfigPath string
+}
+
+func NewIngressClient(kubeconfigPath string) (*ingressClient, error) {
+	return &ingressClient{
+		kubeconfigPath: kubeconfigPath,
+	}, nil
+}
+
+func (c *ingressClient) ListIngresses(ctx context.Context, namespace, contextName string) ([]Ingress, error) {
+	config, err := loadKubeconfigWithContext(c.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	ingresses, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list ingresses in namespace %s: %w", namespace, err)
+	}
+
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list services in namespace %s: %w", namespace, err)
+	}
+
+	portsByName := make(map[string]map[string]int32, len(services.Items))
+	for _, svc := range services.Items {
+		ports := make(map[string]int32, len(svc.Spec.Ports))
+		for _, port := range svc.Spec.Ports {
+			ports[port.Name] = port.Port
+		}
+		portsByName[svc.Name] = ports
+	}
+
+	var result []Ingress
+	for _, ing := range ingresses.Items {
+		ingress := Ingress{
+			Name:      ing.Name,
+			Namespace: ing.Namespace,
+		}
+		if ing.Spec.IngressClassName != nil {
+			ingress.ClassName = *ing.Spec.IngressClassName
+		}
+
+		for _, rule := range ing.Spec.Rules {
+			ingressRule := IngressRule{Host: rule.Host}
+			if rule.HTTP != nil {
+				for _, path := range rule.HTTP.Paths {
+					ingressPath, ok := convertIngressPath(path.Path, path.PathType, path.Backend, portsByName)
+					if ok {
+						ingressRule.Paths = append(ingressRule.Paths, ingressPath)
+					}
+				}
+			}
+			if ing.Spec.DefaultBackend != nil && !hasRootPath(ingressRule.Paths) {
+				if ingressPath, ok := convertIngressPath("/", nil, *ing.Spec.DefaultBackend, portsByName); ok {
+					ingressRule.Paths = append(ingressRule.Paths, ingressPath)
+				}
+			}
+			ingress.Rules = append(ingress.Rules, ingressRule)
+		}
+
+		result = append(result, ingress)
+	}
+
+	return result, nil
+}
+
+func convertIngressPath(path string, pathType *networkingv1.PathType, backend networkingv1.IngressBackend, portsByName map[string]map[string]int32) (IngressPath, bool) {
+	if backend.Service == nil {
+		return IngressPath{}, false
+	}
+
+	port := backend.Service.Port.Number
+	if port == 0 && backend.Service.Port.Name != "" {
+		port = portsByName[backend.Service.Name][backend.Service.Port.Name]
+	}
+	if port == 0 {
+		return IngressPath{}, false
+	}
+
+	if path == "" {
+		path = "/"
+	}
+	ingressPathType := string(networkingv1.PathTypePrefix)
+	if pathType != nil {
+		ingressPathType = string(*pathType)
+	}
+
+	return IngressPath{
+		Path:        path,
+		PathType:    ingressPathType,
+		ServiceName: backend.Service.Name,
+		ServicePort: port,
+	}, true
+}
+
+func hasRootPath(paths []IngressPath) bool {
+	for _, path := range paths {
+		if path.Path == "/" {
+			return true
+		}
+	}
+	return false
+}
@@ -3,9 +3,13 @@ package kube
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/byoungmin/kube-service-tunnel/internal/ports"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -15,32 +19,96 @@ type KubeAdapterInterface interface {
 	ListContexts(ctx context.Context) ([]Context, error)
 	ListNamespaces(ctx context.Context, contextName string) ([]string, error)
 	ListServices(ctx context.Context, namespace, contextName string) ([]Service, error)
+	ListIngresses(ctx context.Context, namespace, contextName string) ([]Ingress, error)
+	ListHTTPRoutes(ctx context.Context, namespace, contextName string) ([]HTTPRoute, error)
+	ListWorkloads(ctx context.Context, namespace, contextName string) ([]Workload, error)
+	ListPods(ctx context.Context, namespace, contextName string) ([]Pod, error)
+	ListServicePods(ctx context.Context, contextName string, svc Service) ([]Pod, error)
 
 	StopAllPortForwards()
-	RegisterAllServicesForContext(contextName string, usedPorts map[int32]bool, services []Service) ([]ServiceTunnel, error)
-	RegisterServicePortForward(contextName, serviceName, namespace string, usedPorts map[int32]bool) (ServiceTunnel, error)
+	RegisterAllServicesForContext(ctx context.Context, contextName string, usedPorts map[int32]bool, registered map[string]bool, services []Service) ([]ServiceTunnel, []RegistrationResult, error)
+	PlanServiceTunnels(ctx context.Context, contextName string, services []Service) ([]ServiceTunnel, []RegistrationResult, error)
+	PlanServiceProxy(ctx context.Context, contextName, serviceName, namespace string, servicePort int32) (ServiceTunnel, error)
+	PlanServiceProxies(ctx context.Context, contextName string, services []Service) ([]ServiceTunnel, []RegistrationResult, error)
+	ServiceProxyTransport(contextName string) (http.RoundTripper, error)
+	RegisterServicePortForward(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, usedPorts map[int32]bool) (ServiceTunnel, error)
+	RegisterServicePodPortForward(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, pod string, usedPorts map[int32]bool) (ServiceTunnel, error)
 	UnregisterServicePortForward(contextName, namespace, pod string, remotePort int32) error
+	RegisterWorkloadPortForwards(ctx context.Context, contextName, namespace, kind, name string, usedPorts map[int32]bool) ([]ServiceTunnel, error)
+	RegisterPodPortForwards(ctx context.Context, contextName, namespace, pod string, usedPorts map[int32]bool) ([]ServiceTunnel, error)
+	StartRelay(ctx context.Context, contextName, namespace, targetHost string, targetPort, localPort int32, usedPorts map[int32]bool) (ServiceTunnel, error)
+	DeleteRelay(contextName, namespace, pod string) error
+	DeleteAllRelays()
+	CollectOrphanRelays(ctx context.Context, contextName string, namespaces []string) error
+	SyncHeadlessForwards(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, current []PodForward, usedPorts map[int32]bool) ([]PodForward, []PodForward, error)
 }
 
 type ServiceTunnel struct {
+	Context      string
+	Namespace    string
+	DNSURL       string
+	ServiceName  string
+	ServicePort  int32
+	Kind         string
+	Pod          string
+	LocalPort    int32
+	RemotePort   int32
+	Protocol     string
+	Headless     bool
+	ExternalName string
+	ProxyURL     string
+	Transport    string
+	Backends     []PodForward
+}
+
+type PodForward struct {
+	Pod        string
+	Host       string
+	LocalPort  int32
+	RemotePort int32
+	Transport  string
+}
+
+type LostForward struct {
 	Context    string
 	Namespace  string
-	DNSURL     string
 	Pod        string
 	LocalPort  int32
 	RemotePort int32
+	Transport  string
+}
+
+type Options struct {
+	Replicas            int
+	ExcludeServiceTypes []string
+	Relay               RelayOptions
+	Ports               ports.AllocatorInterface
+	OnForwardLost       func(LostForward)
+	OnRelayError        func(error)
 }
 
 type kubeAdapter struct {
 	kubeconfigPath    string
+	options           Options
 	contextClient     ContextClientInterface
 	namespaceClient   NamespaceInterface
 	podClient         PodInterface
+	endpointClient    EndpointSliceInterface
 	serviceClient     ServiceInterface
+	ingressClient     IngressInterface
+	httpRouteClient   HTTPRouteInterface
+	workloadClient    WorkloadInterface
 	portForwardClient PortForwardClientInterface
+	ports             ports.AllocatorInterface
+	relays            map[string]relayPod
+	relayCollected    map[string]bool
+	relaySession      string
+	relayOwner        string
+	relayBeating      bool
+	relayMu           sync.Mutex
 }
 
-func NewKubeAdapter(kubeconfigPath string) (KubeAdapterInterface, error) {
+func NewKubeAdapter(kubeconfigPath string, options Options) (KubeAdapterInterface, error) {
 	ctxClient, err := NewContextClient(kubeconfigPath)
 	if err != nil {
 		return nil, err
@@ -56,20 +124,59 @@ func NewKubeAdapter(kubeconfigPath string) (KubeAdapterInterface, error) {
 		return nil, err
 	}
 
+	epClient, err := NewEndpointSliceClient(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
 	svcClient, err := NewServiceClient(kubeconfigPath)
 	if err != nil {
 		return nil, err
 	}
 
-	pfClient := NewPortForwardClient()
+	ingClient, err := NewIngressClient(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	routeClient, err := NewHTTPRouteClient(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	wlClient, err := NewWorkloadClient(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	allocator := options.Ports
+	if allocator == nil {
+		allocator, err = ports.NewAllocator(0, 0, "", nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pfClient := NewPortForwardClient(allocator, options.OnForwardLost)
+	relaySession, relayOwner := newRelaySession()
 
 	return &kubeAdapter{
 		kubeconfigPath:    kubeconfigPath,
+		options:           options,
 		contextClient:     ctxClient,
 		namespaceClient:   nsClient,
 		podClient:         pClient,
+		endpointClient:    epClient,
 		serviceClient:     svcClient,
+		ingressClient:     ingClient,
+		httpRouteClient:   routeClient,
+		workloadClient:    wlClient,
 		portForwardClient: pfClient,
+		ports:             allocator,
+		relays:            make(map[string]relayPod),
+		relayCollected:    make(map[string]bool),
+		relaySession:      relaySession,
+		relayOwner:        relayOwner,
 	}, nil
 }
 
@@ -125,233 +232,378 @@ func (m *kubeAdapter) ListNamespaces(ctx context.Context, contextName string) ([
 }
 
 func (m *kubeAdapter) ListServices(ctx context.Context, namespace, contextName string) ([]Service, error) {
-	return m.serviceClient.ListServices(ctx, namespace, contextName)
+	services, err := m.serviceClient.ListServices(ctx, namespace, contextName)
+	if err != nil || len(m.options.ExcludeServiceTypes) == 0 {
+		return services, err
+	}
+
+	var result []Service
+	for _, svc := range services {
+		if !m.isExcludedServiceType(svc.Type) {
+			result = append(result, svc)
+		}
+	}
+	return result, nil
+}
+
+func (m *kubeAdapter) isExcludedServiceType(serviceType string) bool {
+	for _, excluded := range m.options.ExcludeServiceTypes {
+		if strings.EqualFold(excluded, serviceType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *kubeAdapter) ListIngresses(ctx context.Context, namespace, contextName string) ([]Ingress, error) {
+	return m.ingressClient.ListIngresses(ctx, namespace, contextName)
+}
+
+func (m *kubeAdapter) ListHTTPRoutes(ctx context.Context, namespace, contextName string) ([]HTTPRoute, error) {
+	return m.httpRouteClient.ListHTTPRoutes(ctx, namespace, contextName)
 }
 
 func (m *kubeAdapter) StopAllPortForwards() {
 	m.portForwardClient.StopAllPortForwards()
 }
 
-func (m *kubeAdapter) RegisterAllServicesForContext(contextName string, usedPorts map[int32]bool, services []Service) ([]ServiceTunnel, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
+func (m *kubeAdapter) RegisterAllServicesForContext(ctx context.Context, contextName string, usedPorts map[int32]bool, registered map[string]bool, services []Service) ([]ServiceTunnel, []RegistrationResult, error) {
 	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
 	if err != nil {
-		return nil, fmt.Errorf("load kubeconfig: %w", err)
+		return nil, nil, fmt.Errorf("load kubeconfig: %w", err)
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("create kubernetes client: %w", err)
+		return nil, nil, fmt.Errorf("create kubernetes client: %w", err)
 	}
 
-	var tunnels []ServiceTunnel
-	if len(services) > 0 {
-		// Group services by namespace
-		servicesByNamespace := make(map[string][]Service)
-		for _, svc := range services {
-			servicesByNamespace[svc.Namespace] = append(servicesByNamespace[svc.Namespace], svc)
-		}
-
-		for ns, nsServices := range servicesByNamespace {
-			nsTunnels := m.registerServices(ctx, contextName, ns, nsServices, usedPorts, config, clientset)
-			tunnels = append(tunnels, nsTunnels...)
-		}
-	} else {
-		namespaces, err := m.namespaceClient.ListNonSystemNamespaces(ctx, contextName)
-		if err != nil {
-			return nil, fmt.Errorf("list namespaces: %w", err)
-		}
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	services, results, err := m.collectServices(listCtx, contextName, services)
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("list namespaces: %w", err)
+	}
 
-		for _, ns := range namespaces {
-			nsTunnels, err := m.registerNamespaceServices(ctx, contextName, ns, usedPorts, config, clientset)
-			if err != nil {
-				continue
-			}
-			tunnels = append(tunnels, nsTunnels...)
-		}
+	tunnels, serviceResults, err := m.registerServices(ctx, contextName, services, usedPorts, registered, config, clientset)
+	if err != nil {
+		return nil, nil, err
 	}
+	results = append(results, serviceResults...)
 
-	if len(tunnels) == 0 {
-		return nil, fmt.Errorf("no services found to register")
+	if len(results) == 0 {
+		return nil, nil, fmt.Errorf("no services found to register")
 	}
 
-	return tunnels, nil
+	return tunnels, results, nil
 }
 
-func (m *kubeAdapter) registerServices(
-	ctx context.Context,
-	contextName string,
-	namespace string,
-	services []Service,
-	usedPorts map[int32]bool,
-	config *rest.Config,
-	clientset kubernetes.Interface,
-) []ServiceTunnel {
-	var tunnels []ServiceTunnel
+func (m *kubeAdapter) PlanServiceTunnels(ctx context.Context, contextName string, services []Service) ([]ServiceTunnel, []RegistrationResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	services, results, err := m.collectServices(ctx, contextName, services)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list namespaces: %w", err)
+	}
 
+	var tunnels []ServiceTunnel
 	for _, svc := range services {
-		if svc.ClusterIP == "" {
-			continue
-		}
+		result := RegistrationResult{Namespace: svc.Namespace, Service: svc.Name}
 
-		httpPort := PickHTTPPort(&svc)
+		httpPort, reason := pickBulkPort(&svc)
 		if httpPort == nil {
+			result.Reason = reason
+			results = append(results, result)
 			continue
 		}
-
-		pod, podPort, err := FindPodAndPortForService(ctx, m.podClient, namespace, contextName, svc.Selector, httpPort)
-		if err != nil {
-			continue
-		}
-
-		localPort, err := findAvailablePort(40000, usedPorts)
-		if err != nil {
-			continue
-		}
-
-		if err := m.portForwardClient.StartPortForward(contextName, namespace, pod.Name, localPort, podPort, config, clientset); err != nil {
-			continue
-		}
-
-		serviceDNS := BuildServiceDNS(svc.Name, namespace, httpPort.Port)
+		result.DNSURL = BuildServiceDNS(svc.Name, svc.Namespace, httpPort.Port)
+		results = append(results, result)
 
 		tunnels = append(tunnels, ServiceTunnel{
-			Context:    contextName,
-			Namespace:  namespace,
-			DNSURL:     serviceDNS,
-			Pod:        pod.Name,
-			LocalPort:  localPort,
-			RemotePort: podPort,
+			Context:     contextName,
+			Namespace:   svc.Namespace,
+			DNSURL:      result.DNSURL,
+			ServiceName: svc.Name,
+			ServicePort: httpPort.Port,
+			Protocol:    DetectPortProtocol(httpPort),
 		})
+	}
 
-		usedPorts[localPort] = true
+	if len(results) == 0 {
+		return nil, nil, fmt.Errorf("no services found to register")
 	}
 
-	return tunnels
+	return tunnels, results, nil
 }
 
-func (m *kubeAdapter) registerNamespaceServices(
-	ctx context.Context,
-	contextName string,
-	namespace string,
-	usedPorts map[int32]bool,
-	config *rest.Config,
-	clientset kubernetes.Interface,
-) ([]ServiceTunnel, error) {
-	services, err := m.ListServices(ctx, namespace, contextName)
+func (m *kubeAdapter) RegisterServicePortForward(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, usedPorts map[int32]bool) (ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	targetService, err := m.findService(ctx, contextName, namespace, serviceName)
 	if err != nil {
-		return nil, err
+		return ServiceTunnel{}, err
 	}
 
-	var tunnels []ServiceTunnel
-
-	for _, svc := range services {
-		if svc.ClusterIP == "" {
-			continue
+	if targetService.IsExternalName() {
+		tunnel := ServiceTunnel{
+			Context:      contextName,
+			Namespace:    namespace,
+			DNSURL:       BuildServiceDNS(serviceName, namespace, 80),
+			ServiceName:  serviceName,
+			ExternalName: targetService.ExternalName,
 		}
+		if port := findServicePort(targetService, servicePort); port != nil {
+			tunnel.ServicePort = port.Port
+		} else if len(targetService.Ports) > 0 {
+			tunnel.ServicePort = targetService.Ports[0].Port
+		}
+		return tunnel, nil
+	}
 
-		httpPort := PickHTTPPort(&svc)
+	var httpPort *ServicePort
+	if servicePort != 0 {
+		httpPort = findServicePort(targetService, servicePort)
 		if httpPort == nil {
-			continue
+			return ServiceTunnel{}, fmt.Errorf("port %d not found for service %s/%s", servicePort, namespace, serviceName)
 		}
-
-		pod, podPort, err := FindPodAndPortForService(ctx, m.podClient, namespace, contextName, svc.Selector, httpPort)
-		if err != nil {
-			continue
+	} else {
+		httpPort = PickHTTPPort(targetService)
+		if httpPort == nil {
+			return ServiceTunnel{}, fmt.Errorf("no HTTP port found for service %s/%s", namespace, serviceName)
 		}
+	}
 
-		localPort, err := findAvailablePort(40000, usedPorts)
-		if err != nil {
-			continue
-		}
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
 
-		if err := m.portForwardClient.StartPortForward(contextName, namespace, pod.Name, localPort, podPort, config, clientset); err != nil {
-			continue
-		}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("create kubernetes client: %w", err)
+	}
 
-		serviceDNS := BuildServiceDNS(svc.Name, namespace, httpPort.Port)
+	return m.startServiceForwards(ctx, contextName, targetService, httpPort, usedPorts, config, clientset)
+}
 
-		tunnels = append(tunnels, ServiceTunnel{
-			Context:    contextName,
-			Namespace:  namespace,
-			DNSURL:     serviceDNS,
-			Pod:        pod.Name,
-			LocalPort:  localPort,
-			RemotePort: podPort,
-		})
+func (m *kubeAdapter) RegisterServicePodPortForward(ctx context.Context, contextName, serviceName, namespace string, servicePort int32, podName string, usedPorts map[int32]bool) (ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-		usedPorts[localPort] = true
+	targetService, err := m.findService(ctx, contextName, namespace, serviceName)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+	if targetService.IsExternalName() {
+		return ServiceTunnel{}, fmt.Errorf("service %s/%s is an ExternalName service without pods", namespace, serviceName)
+	}
+
+	httpPort := findServicePort(targetService, servicePort)
+	if httpPort == nil {
+		return ServiceTunnel{}, fmt.Errorf("port %d not found for service %s/%s", servicePort, namespace, serviceName)
+	}
+
+	pod, err := m.podClient.GetPod(ctx, namespace, contextName, podName)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+	if pod.Status != string(corev1.PodRunning) {
+		return ServiceTunnel{}, fmt.Errorf("pod %s/%s is %s, not Running", namespace, podName, pod.Status)
+	}
+
+	podPort, err := resolveBackendPort(ctx, m.endpointClient, contextName, targetService, httpPort, pod)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	tunnel := ServiceTunnel{
+		Context:     contextName,
+		Namespace:   namespace,
+		DNSURL:      BuildServiceDNS(serviceName, namespace, httpPort.Port),
+		ServiceName: serviceName,
+		ServicePort: httpPort.Port,
+		Protocol:    DetectPortProtocol(httpPort),
+	}
+	forward, err := m.startPodForward(ctx, tunnel, ServiceBackend{Pod: pod.Name, Port: podPort}, buildLocalPortKey(tunnel), usedPorts, config, clientset)
+	if err != nil {
+		return ServiceTunnel{}, err
 	}
 
-	return tunnels, nil
+	tunnel.Pod = forward.Pod
+	tunnel.LocalPort = forward.LocalPort
+	tunnel.RemotePort = forward.RemotePort
+	tunnel.Transport = forward.Transport
+	return tunnel, nil
 }
 
-func (m *kubeAdapter) RegisterServicePortForward(contextName, serviceName, namespace string, usedPorts map[int32]bool) (ServiceTunnel, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+func (m *kubeAdapter) ListServicePods(ctx context.Context, contextName string, svc Service) ([]Pod, error) {
+	return FindServicePods(ctx, m.endpointClient, m.podClient, contextName, &svc)
+}
 
+func (m *kubeAdapter) findService(ctx context.Context, contextName, namespace, serviceName string) (*Service, error) {
 	services, err := m.ListServices(ctx, namespace, contextName)
 	if err != nil {
-		return ServiceTunnel{}, fmt.Errorf("list services: %w", err)
+		return nil, fmt.Errorf("list services: %w", err)
 	}
 
-	var targetService *Service
 	for _, svc := range services {
 		if svc.Name == serviceName && svc.Namespace == namespace {
-			if svc.ClusterIP == "" {
-				return ServiceTunnel{}, fmt.Errorf("service %s/%s has no ClusterIP", namespace, serviceName)
+			if svc.ClusterIP == "" && !svc.IsExternalName() {
+				return nil, fmt.Errorf("service %s/%s has no ClusterIP", namespace, serviceName)
 			}
-			targetService = &svc
-			break
+			return &svc, nil
 		}
 	}
+	return nil, fmt.Errorf("service %s/%s not found in current namespace", namespace, serviceName)
+}
 
-	if targetService == nil {
-		return ServiceTunnel{}, fmt.Errorf("service %s/%s not found in current namespace", namespace, serviceName)
+func (m *kubeAdapter) startServiceForwards(
+	ctx context.Context,
+	contextName string,
+	svc *Service,
+	httpPort *ServicePort,
+	usedPorts map[int32]bool,
+	config *rest.Config,
+	clientset kubernetes.Interface,
+) (ServiceTunnel, error) {
+	tunnel := ServiceTunnel{
+		Context:     contextName,
+		Namespace:   svc.Namespace,
+		DNSURL:      BuildServiceDNS(svc.Name, svc.Namespace, httpPort.Port),
+		ServiceName: svc.Name,
+		ServicePort: httpPort.Port,
+		Protocol:    DetectPortProtocol(httpPort),
+		Headless:    svc.IsHeadless(),
 	}
 
-	httpPort := PickHTTPPort(targetService)
-	if httpPort == nil {
-		return ServiceTunnel{}, fmt.Errorf("no HTTP port found for service %s/%s", namespace, serviceName)
+	limit := max(m.options.Replicas, 1)
+	if tunnel.Headless {
+		limit = 0
 	}
 
-	pod, podPort, err := FindPodAndPortForService(ctx, m.podClient, namespace, contextName, targetService.Selector, httpPort)
+	backends, err := ResolveServiceBackends(ctx, m.endpointClient, m.podClient, contextName, svc, httpPort, limit)
 	if err != nil {
-		return ServiceTunnel{}, fmt.Errorf("find matching pods: %w", err)
+		return ServiceTunnel{}, &registrationError{reason: SkipReasonNoPods, err: fmt.Errorf("find matching pods: %w", err)}
 	}
 
-	localPort, err := findAvailablePort(40000, usedPorts)
+	if len(backends) == 1 && !tunnel.Headless {
+		forward, err := m.startPodForward(ctx, tunnel, backends[0], buildLocalPortKey(tunnel), usedPorts, config, clientset)
+		if err != nil {
+			return ServiceTunnel{}, err
+		}
+
+		tunnel.Pod = forward.Pod
+		tunnel.LocalPort = forward.LocalPort
+		tunnel.RemotePort = forward.RemotePort
+		tunnel.Transport = forward.Transport
+		return tunnel, nil
+	}
+
+	balancerPort, err := m.ports.Allocate(buildLocalPortKey(tunnel), usedPorts)
 	if err != nil {
 		return ServiceTunnel{}, fmt.Errorf("find available port: %w", err)
 	}
+	usedPorts[balancerPort] = true
 
-	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
-	if err != nil {
-		return ServiceTunnel{}, fmt.Errorf("load kubeconfig: %w", err)
+	for _, backend := range backends {
+		forward, err := m.startPodForward(ctx, tunnel, backend, buildBackendPortKey(tunnel, backend), usedPorts, config, clientset)
+		if err != nil {
+			if canceled(ctx) {
+				tunnel.LocalPort = balancerPort
+				m.stopServiceTunnel(tunnel)
+				return ServiceTunnel{}, err
+			}
+			continue
+		}
+		tunnel.Backends = append(tunnel.Backends, forward)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return ServiceTunnel{}, fmt.Errorf("create kubernetes client: %w", err)
+	if len(tunnel.Backends) == 0 {
+		delete(usedPorts, balancerPort)
+		m.ports.Release(balancerPort)
+		return ServiceTunnel{}, fmt.Errorf("start port forward: no pod of %s/%s could be forwarded", svc.Namespace, svc.Name)
 	}
 
-	err = m.portForwardClient.StartPortForward(contextName, namespace, pod.Name, localPort, podPort, config, clientset)
+	tunnel.Pod = tunnel.Backends[0].Pod
+	tunnel.LocalPort = balancerPort
+	tunnel.RemotePort = tunnel.Backends[0].RemotePort
+	tunnel.Transport = tunnel.Backends[0].Transport
+	return tunnel, nil
+}
+
+func (m *kubeAdapter) startPodForward(
+	ctx context.Context,
+	tunnel ServiceTunnel,
+	backend ServiceBackend,
+	key string,
+	usedPorts map[int32]bool,
+	config *rest.Config,
+	clientset kubernetes.Interface,
+) (PodForward, error) {
+	localPort, err := m.ports.Allocate(key, usedPorts)
 	if err != nil {
-		return ServiceTunnel{}, fmt.Errorf("start port forward: %w", err)
+		return PodForward{}, fmt.Errorf("find available port: %w", err)
 	}
 
-	serviceDNS := BuildServiceDNS(serviceName, namespace, httpPort.Port)
+	transport, err := m.portForwardClient.StartPortForward(ctx, tunnel.Context, tunnel.Namespace, backend.Pod, localPort, backend.Port, config, clientset)
+	if err != nil {
+		return PodForward{}, fmt.Errorf("start port forward: %w", err)
+	}
+	usedPorts[localPort] = true
 
-	return ServiceTunnel{
-		Context:    contextName,
-		Namespace:  namespace,
-		DNSURL:     serviceDNS,
-		Pod:        pod.Name,
+	forward := PodForward{
+		Pod:        backend.Pod,
 		LocalPort:  localPort,
-		RemotePort: podPort,
-	}, nil
+		RemotePort: backend.Port,
+		Transport:  transport,
+	}
+	if tunnel.Headless {
+		forward.Host = BuildPodDNS(backend.Pod, tunnel.ServiceName, tunnel.Namespace, tunnel.ServicePort)
+	}
+	return forward, nil
+}
+
+func buildLocalPortKey(tunnel ServiceTunnel) string {
+	return fmt.Sprintf("%s/%s/%s:%d", tunnel.Context, tunnel.Namespace, tunnel.ServiceName, tunnel.ServicePort)
+}
+
+func buildBackendPortKey(tunnel ServiceTunnel, backend ServiceBackend) string {
+	if !tunnel.Headless {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s/%s:%d", tunnel.Context, tunnel.Namespace, tunnel.ServiceName, backend.Pod, tunnel.ServicePort)
+}
+
+func (m *kubeAdapter) stopServiceTunnel(tunnel ServiceTunnel) {
+	if len(tunnel.Backends) == 0 {
+		if tunnel.Pod != "" {
+			m.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, tunnel.Pod, tunnel.RemotePort)
+		}
+		return
+	}
+
+	for _, backend := range tunnel.Backends {
+		m.UnregisterServicePortForward(tunnel.Context, tunnel.Namespace, backend.Pod, backend.RemotePort)
+	}
+	m.ports.Release(tunnel.LocalPort)
+}
+
+func (m *kubeAdapter) stopServiceTunnels(tunnels []ServiceTunnel) {
+	for _, tunnel := range tunnels {
+		m.stopServiceTunnel(tunnel)
+	}
 }
 
 func (m *kubeAdapter) UnregisterServicePortForward(contextName, namespace, pod string, remotePort int32) error {
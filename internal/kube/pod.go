@@ -3,7 +3,9 @@ package kube
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -18,6 +20,9 @@ type Pod struct {
 	Name      string
 	Namespace string
 	Status    string
+	Ready     bool
+	Restarts  int32
+	Created   time.Time
 	Ports     []PodPort
 	Labels    map[string]string
 }
@@ -25,6 +30,8 @@ type Pod struct {
 type PodInterface interface {
 	ListPods(ctx context.Context, namespace, contextName string) ([]Pod, error)
 	FindMatchingPods(ctx context.Context, namespace, contextName string, selector map[string]string) ([]Pod, error)
+	FindSelectedPods(ctx context.Context, namespace, contextName, selector string) ([]Pod, error)
+	GetPod(ctx context.Context, namespace, contextName, name string) (Pod, error)
 }
 
 type podClient struct {
@@ -55,46 +62,15 @@ func (p *podClient) ListPods(ctx context.Context, namespace, contextName string)
 
 	var result []Pod
 	for _, pod := range pods.Items {
-		var ports []PodPort
-		for _, container := range pod.Spec.Containers {
-			for _, port := range container.Ports {
-				ports = append(ports, PodPort{
-					Name:          port.Name,
-					ContainerPort: port.ContainerPort,
-					Protocol:      string(port.Protocol),
-				})
-			}
-		}
-
-		labels := make(map[string]string)
-		if pod.Labels != nil {
-			for k, v := range pod.Labels {
-				labels[k] = v
-			}
-		}
-
-		status := string(pod.Status.Phase)
-		result = append(result, Pod{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-			Status:    status,
-			Ports:     ports,
-			Labels:    labels,
-		})
+		result = append(result, convertPod(pod))
 	}
 
 	return result, nil
 }
 
 func (p *podClient) FindMatchingPods(ctx context.Context, namespace, contextName string, selector map[string]string) ([]Pod, error) {
-	config, err := loadKubeconfigWithContext(p.kubeconfigPath, contextName)
-	if err != nil {
-		return nil, fmt.Errorf("load kubeconfig: %w", err)
-	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("empty selector matches no pods")
 	}
 
 	labelSelector := metav1.LabelSelector{
@@ -105,8 +81,26 @@ func (p *podClient) FindMatchingPods(ctx context.Context, namespace, contextName
 		return nil, fmt.Errorf("create label selector: %w", err)
 	}
 
+	return p.FindSelectedPods(ctx, namespace, contextName, selectorString.String())
+}
+
+func (p *podClient) FindSelectedPods(ctx context.Context, namespace, contextName, selector string) ([]Pod, error) {
+	if selector == "" {
+		return nil, fmt.Errorf("empty selector matches no pods")
+	}
+
+	config, err := loadKubeconfigWithContext(p.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
 	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: selectorString.String(),
+		LabelSelector: selector,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list pods with selector in namespace %s: %w", namespace, err)
@@ -114,35 +108,72 @@ func (p *podClient) FindMatchingPods(ctx context.Context, namespace, contextName
 
 	var result []Pod
 	for _, pod := range pods.Items {
-		var ports []PodPort
-		for _, container := range pod.Spec.Containers {
-			for _, port := range container.Ports {
-				ports = append(ports, PodPort{
-					Name:          port.Name,
-					ContainerPort: port.ContainerPort,
-					Protocol:      string(port.Protocol),
-				})
-			}
-		}
+		result = append(result, convertPod(pod))
+	}
+
+	return result, nil
+}
+
+func (p *podClient) GetPod(ctx context.Context, namespace, contextName, name string) (Pod, error) {
+	config, err := loadKubeconfigWithContext(p.kubeconfigPath, contextName)
+	if err != nil {
+		return Pod{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return Pod{}, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return Pod{}, fmt.Errorf("get pod %s/%s: %w", namespace, name, err)
+	}
+
+	return convertPod(*pod), nil
+}
 
-		labels := make(map[string]string)
-		if pod.Labels != nil {
-			for k, v := range pod.Labels {
-				labels[k] = v
-			}
+func convertPod(pod corev1.Pod) Pod {
+	var ports []PodPort
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			ports = append(ports, PodPort{
+				Name:          port.Name,
+				ContainerPort: port.ContainerPort,
+				Protocol:      string(port.Protocol),
+			})
 		}
+	}
 
-		status := string(pod.Status.Phase)
-		result = append(result, Pod{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-			Status:    status,
-			Ports:     ports,
-			Labels:    labels,
-		})
+	labels := make(map[string]string)
+	for k, v := range pod.Labels {
+		labels[k] = v
 	}
 
-	return result, nil
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+
+	return Pod{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Status:    string(pod.Status.Phase),
+		Ready:     isPodReady(pod),
+		Restarts:  restarts,
+		Created:   pod.CreationTimestamp.Time,
+		Ports:     ports,
+		Labels:    labels,
+	}
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
 }
 
 func FindPodAndPortForService(
@@ -163,25 +194,71 @@ func FindPodAndPortForService(
 	}
 
 	pod := pods[0]
-	var podPort int32
+	podPort := resolvePodPort(pod, httpPort)
+	if podPort == 0 {
+		return Pod{}, 0, fmt.Errorf("could not determine pod port")
+	}
 
-	if httpPort.TargetPort > 0 {
-		podPort = httpPort.TargetPort
-	} else {
-		for _, p := range pod.Ports {
-			if p.Name == httpPort.Name || p.ContainerPort == httpPort.Port {
-				podPort = p.ContainerPort
-				break
-			}
+	return pod, podPort, nil
+}
+
+func FindPodsAndPortsForService(
+	ctx context.Context,
+	podClient PodInterface,
+	namespace string,
+	contextName string,
+	selector map[string]string,
+	httpPort *ServicePort,
+	limit int,
+) ([]Pod, []int32, error) {
+	pods, err := podClient.FindMatchingPods(ctx, namespace, contextName, selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("find matching pods: %w", err)
+	}
+
+	var ready []Pod
+	for _, pod := range pods {
+		if pod.Ready {
+			ready = append(ready, pod)
 		}
-		if podPort == 0 && len(pod.Ports) > 0 {
-			podPort = pod.Ports[0].ContainerPort
+	}
+	if len(ready) == 0 {
+		return nil, nil, fmt.Errorf("no ready pods found")
+	}
+
+	var result []Pod
+	var ports []int32
+	for _, pod := range ready {
+		if limit > 0 && len(result) == limit {
+			break
 		}
+		podPort := resolvePodPort(pod, httpPort)
+		if podPort == 0 {
+			continue
+		}
+		result = append(result, pod)
+		ports = append(ports, podPort)
 	}
 
-	if podPort == 0 {
-		return Pod{}, 0, fmt.Errorf("could not determine pod port")
+	if len(result) == 0 {
+		return nil, nil, fmt.Errorf("could not determine pod port")
 	}
 
-	return pod, podPort, nil
+	return result, ports, nil
+}
+
+func resolvePodPort(pod Pod, httpPort *ServicePort) int32 {
+	if httpPort.TargetPort > 0 {
+		return httpPort.TargetPort
+	}
+
+	for _, p := range pod.Ports {
+		if p.Name == httpPort.Name || p.ContainerPort == httpPort.Port {
+			return p.ContainerPort
+		}
+	}
+	if len(pod.Ports) > 0 {
+		return pod.Ports[0].ContainerPort
+	}
+	return 0
 }
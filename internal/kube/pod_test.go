@@ -0,0 +1,146 @@
+package kube
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvertPod(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "api-0",
+			Namespace:         "default",
+			Labels:            map[string]string{"app": "api"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}},
+			{Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}},
+		}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{RestartCount: 2},
+				{RestartCount: 3},
+			},
+		},
+	}
+
+	got := convertPod(pod)
+	want := Pod{
+		Name:      "api-0",
+		Namespace: "default",
+		Status:    "Running",
+		Ready:     true,
+		Restarts:  5,
+		Created:   created,
+		Ports: []PodPort{
+			{Name: "http", ContainerPort: 8080, Protocol: "TCP"},
+			{Name: "metrics", ContainerPort: 9090, Protocol: "TCP"},
+		},
+		Labels: map[string]string{"app": "api"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("convertPod() = %+v, want %+v", got, want)
+	}
+
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+	if convertPod(pod).Ready {
+		t.Error("expected a pod with Ready=False to be not ready")
+	}
+}
+
+func TestFindServicePodsUsesEndpointMembers(t *testing.T) {
+	endpoints := &fakeEndpointClient{found: true, endpoints: []Endpoint{
+		{Pod: "a", Ready: true},
+		{Pod: "b", Ready: false},
+	}}
+	pods := &fakePodClient{pods: []Pod{{Name: "a"}, {Name: "b"}, {Name: "unrelated"}}}
+	svc := &Service{Name: "api", Namespace: "default"}
+
+	got, err := FindServicePods(context.Background(), endpoints, pods, "ctx", svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Pod{{Name: "a"}, {Name: "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindServicePods() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindServicePodsFallsBackToSelector(t *testing.T) {
+	pods := &fakePodClient{pods: []Pod{{Name: "a"}}}
+	svc := &Service{Name: "api", Namespace: "default", Selector: map[string]string{"app": "api"}}
+
+	got, err := FindServicePods(context.Background(), &fakeEndpointClient{}, pods, "ctx", svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("FindServicePods() = %+v, want pod a", got)
+	}
+
+	svc.Selector = nil
+	if _, err := FindServicePods(context.Background(), &fakeEndpointClient{}, pods, "ctx", svc); err == nil {
+		t.Error("expected an error for a service without selector or endpoints")
+	}
+}
+
+func TestResolveBackendPort(t *testing.T) {
+	svc := &Service{Name: "api", Namespace: "default"}
+	httpPort := &ServicePort{Name: "http", Port: 80}
+	pod := Pod{Name: "a", Ports: []PodPort{{Name: "http", ContainerPort: 8080}}}
+
+	endpoints := &fakeEndpointClient{found: true, endpoints: []Endpoint{
+		{Pod: "b", Ports: []EndpointPort{{Name: "http", Port: 7000}}},
+		{Pod: "a", Ports: []EndpointPort{{Name: "http", Port: 8081}}},
+	}}
+	port, err := resolveBackendPort(context.Background(), endpoints, "ctx", svc, httpPort, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 8081 {
+		t.Errorf("port from endpoints = %d, want 8081", port)
+	}
+
+	port, err = resolveBackendPort(context.Background(), &fakeEndpointClient{}, "ctx", svc, httpPort, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 8080 {
+		t.Errorf("port from pod spec = %d, want 8080", port)
+	}
+
+	if _, err := resolveBackendPort(context.Background(), &fakeEndpointClient{}, "ctx", svc, httpPort, Pod{Name: "bare"}); err == nil {
+		t.Error("expected an error for a pod without ports")
+	}
+}
+
+func TestResolvePodPort(t *testing.T) {
+	pod := Pod{Ports: []PodPort{{Name: "metrics", ContainerPort: 9090}, {Name: "web", ContainerPort: 8080}}}
+
+	tests := []struct {
+		name string
+		port ServicePort
+		want int32
+	}{
+		{name: "target port wins", port: ServicePort{Name: "web", Port: 80, TargetPort: 3000}, want: 3000},
+		{name: "matching name", port: ServicePort{Name: "web", Port: 80}, want: 8080},
+		{name: "matching number", port: ServicePort{Name: "other", Port: 9090}, want: 9090},
+		{name: "first port", port: ServicePort{Name: "other", Port: 80}, want: 9090},
+	}
+	for _, tt := range tests {
+		if got := resolvePodPort(pod, &tt.port); got != tt.want {
+			t.Errorf("%s: resolvePodPort() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if got := resolvePodPort(Pod{}, &ServicePort{Port: 80}); got != 0 {
+		t.Errorf("resolvePodPort() without ports = %d, want 0", got)
+	}
+}
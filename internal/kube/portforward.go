@@ -1,26 +1,41 @@
 package kube
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
 
+	"github.com/byoungmin/kube-service-tunnel/internal/ports"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 )
 
+const (
+	PortForwardTransportWebSocket = "websocket"
+	PortForwardTransportSPDY      = "spdy"
+)
+
 type PortForwardClientInterface interface {
-	StartPortForward(contextName, namespace, pod string, localPort, remotePort int32, config *rest.Config, clientset kubernetes.Interface) error
+	StartPortForward(ctx context.Context, contextName, namespace, pod string, localPort, remotePort int32, config *rest.Config, clientset kubernetes.Interface) (string, error)
 	StopPortForward(key string) error
 	StopAllPortForwards()
 }
 
 type portForwardClient struct {
 	forwards map[string]*PortForward
+	sessions map[string]*podSession
+	ports    ports.AllocatorInterface
+	onLost   func(LostForward)
 	mu       sync.RWMutex
 }
 
@@ -31,12 +46,28 @@ type PortForward struct {
 	Pod        string
 	LocalPort  int32
 	RemotePort int32
+	Transport  string
 	StopCh     chan struct{}
+	session    *podSession
+	listeners  []net.Listener
+}
+
+type podSession struct {
+	key       string
+	conn      httpstream.Connection
+	transport string
+	err       error
+	ready     chan struct{}
+	forwards  map[string]*PortForward
+	requestID atomic.Int64
 }
 
-func NewPortForwardClient() PortForwardClientInterface {
+func NewPortForwardClient(allocator ports.AllocatorInterface, onLost func(LostForward)) PortForwardClientInterface {
 	return &portForwardClient{
 		forwards: make(map[string]*PortForward),
+		sessions: make(map[string]*podSession),
+		ports:    allocator,
+		onLost:   onLost,
 	}
 }
 
@@ -44,18 +75,36 @@ func BuildPortForwardKey(contextName, namespace, pod string, remotePort int32) s
 	return fmt.Sprintf("%s:%s:%s:%d", contextName, namespace, pod, remotePort)
 }
 
-func (p *portForwardClient) StartPortForward(contextName, namespace, pod string, localPort, remotePort int32, config *rest.Config, clientset kubernetes.Interface) error {
+func buildPodSessionKey(contextName, namespace, pod string) string {
+	return fmt.Sprintf("%s:%s:%s", contextName, namespace, pod)
+}
+
+func (p *portForwardClient) StartPortForward(ctx context.Context, contextName, namespace, pod string, localPort, remotePort int32, config *rest.Config, clientset kubernetes.Interface) (string, error) {
 	key := BuildPortForwardKey(contextName, namespace, pod, remotePort)
+	sessionKey := buildPodSessionKey(contextName, namespace, pod)
 
 	p.mu.Lock()
 	if _, exists := p.forwards[key]; exists {
 		p.mu.Unlock()
-		return fmt.Errorf("port forward already exists: %s", key)
+		p.ports.Release(localPort)
+		return "", fmt.Errorf("port forward already exists: %s", key)
 	}
 
-	stopCh := make(chan struct{}, 1)
-	readyCh := make(chan struct{})
-	errorCh := make(chan error, 1)
+	listeners, err := p.ports.Take(localPort)
+	if err != nil {
+		p.mu.Unlock()
+		return "", err
+	}
+
+	session, exists := p.sessions[sessionKey]
+	if !exists {
+		session = &podSession{
+			key:      sessionKey,
+			ready:    make(chan struct{}),
+			forwards: make(map[string]*PortForward),
+		}
+		p.sessions[sessionKey] = session
+	}
 
 	forward := &PortForward{
 		Key:        key,
@@ -64,22 +113,113 @@ func (p *portForwardClient) StartPortForward(contextName, namespace, pod string,
 		Pod:        pod,
 		LocalPort:  localPort,
 		RemotePort: remotePort,
-		StopCh:     stopCh,
+		StopCh:     make(chan struct{}),
+		session:    session,
+		listeners:  listeners,
 	}
-
 	p.forwards[key] = forward
+	session.forwards[key] = forward
 	p.mu.Unlock()
 
-	go startPortForwardGoroutine(config, clientset, namespace, pod, localPort, remotePort, stopCh, readyCh, errorCh)
+	if !exists {
+		go p.dialSession(session, config, clientset, namespace, pod)
+	}
 
 	select {
-	case <-readyCh:
-		return nil
-	case err := <-errorCh:
-		p.mu.Lock()
-		defer p.mu.Unlock()
+	case <-session.ready:
+	case <-ctx.Done():
+		p.StopPortForward(key)
+		return "", ctx.Err()
+	}
+
+	if session.err != nil {
+		p.StopPortForward(key)
+		return "", session.err
+	}
+
+	p.mu.Lock()
+	if p.forwards[key] != forward {
+		p.mu.Unlock()
+		return "", fmt.Errorf("port forward stopped while starting: %s", key)
+	}
+	forward.Transport = session.transport
+	p.mu.Unlock()
+
+	for _, listener := range listeners {
+		go session.serve(listener, remotePort)
+	}
+	return session.transport, nil
+}
+
+func (p *portForwardClient) dialSession(session *podSession, config *rest.Config, clientset kubernetes.Interface, namespace, pod string) {
+	defer close(session.ready)
+
+	reqURL := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").URL()
+
+	dialer, err := newPortForwardDialer(config, reqURL, &session.transport)
+	if err != nil {
+		session.err = err
+		p.dropSession(session)
+		return
+	}
+
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		session.err = fmt.Errorf("forward ports: %w", err)
+		p.dropSession(session)
+		return
+	}
+	session.conn = conn
+
+	p.mu.RLock()
+	active := p.sessions[session.key] == session
+	p.mu.RUnlock()
+	if !active {
+		conn.Close()
+	}
+
+	go p.watchSession(session)
+}
+
+func (p *portForwardClient) dropSession(session *podSession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sessions[session.key] == session {
+		delete(p.sessions, session.key)
+	}
+}
+
+func (p *portForwardClient) watchSession(session *podSession) {
+	<-session.conn.CloseChan()
+
+	p.mu.Lock()
+	if p.sessions[session.key] == session {
+		delete(p.sessions, session.key)
+	}
+	forwards := make([]*PortForward, 0, len(session.forwards))
+	for key, forward := range session.forwards {
 		delete(p.forwards, key)
-		return err
+		forwards = append(forwards, forward)
+	}
+	session.forwards = make(map[string]*PortForward)
+	p.mu.Unlock()
+
+	for _, forward := range forwards {
+		p.stopForward(forward)
+		if p.onLost != nil {
+			p.onLost(LostForward{
+				Context:    forward.Context,
+				Namespace:  forward.Namespace,
+				Pod:        forward.Pod,
+				LocalPort:  forward.LocalPort,
+				RemotePort: forward.RemotePort,
+				Transport:  forward.Transport,
+			})
+		}
 	}
 }
 
@@ -91,25 +231,51 @@ func (p *portForwardClient) StopPortForward(key string) error {
 		return fmt.Errorf("port forward not found: %s", key)
 	}
 	delete(p.forwards, key)
+
+	session := forward.session
+	delete(session.forwards, key)
+	idle := len(session.forwards) == 0
+	if idle && p.sessions[session.key] == session {
+		delete(p.sessions, session.key)
+	}
 	p.mu.Unlock()
 
-	safeCloseChannel(forward.StopCh)
+	p.stopForward(forward)
+	if idle {
+		session.close()
+	}
 	return nil
 }
 
 func (p *portForwardClient) StopAllPortForwards() {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	forwards := make([]*PortForward, 0, len(p.forwards))
 	for _, forward := range p.forwards {
 		forwards = append(forwards, forward)
 	}
+	sessions := make([]*podSession, 0, len(p.sessions))
+	for _, session := range p.sessions {
+		session.forwards = make(map[string]*PortForward)
+		sessions = append(sessions, session)
+	}
 	p.forwards = make(map[string]*PortForward)
+	p.sessions = make(map[string]*podSession)
+	p.mu.Unlock()
 
 	for _, forward := range forwards {
-		safeCloseChannel(forward.StopCh)
+		p.stopForward(forward)
+	}
+	for _, session := range sessions {
+		session.close()
+	}
+}
+
+func (p *portForwardClient) stopForward(forward *PortForward) {
+	safeCloseChannel(forward.StopCh)
+	for _, listener := range forward.listeners {
+		listener.Close()
 	}
+	p.ports.Release(forward.LocalPort)
 }
 
 func safeCloseChannel(ch chan struct{}) {
@@ -120,65 +286,110 @@ func safeCloseChannel(ch chan struct{}) {
 	}
 }
 
-func safeCloseErrorChannel(ch chan error) {
+func (s *podSession) close() {
 	select {
-	case <-ch:
+	case <-s.ready:
 	default:
-		close(ch)
+		return
+	}
+	if s.conn != nil {
+		s.conn.Close()
 	}
 }
 
-func startPortForwardGoroutine(config *rest.Config, clientset kubernetes.Interface, namespace, pod string, localPort, remotePort int32, stopCh, readyCh chan struct{}, errorCh chan error) {
-	defer func() {
-		safeCloseChannel(readyCh)
-		safeCloseErrorChannel(errorCh)
-	}()
+func (s *podSession) serve(listener net.Listener, remotePort int32) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConnection(conn, remotePort)
+	}
+}
 
-	reqURL := clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Namespace(namespace).
-		Name(pod).
-		SubResource("portforward").URL()
+func (s *podSession) handleConnection(conn net.Conn, remotePort int32) {
+	defer conn.Close()
 
-	transport, upgrader, err := spdy.RoundTripperFor(config)
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(remotePort)))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.FormatInt(s.requestID.Add(1), 10))
+
+	errorStream, err := s.conn.CreateStream(headers)
 	if err != nil {
-		errorCh <- fmt.Errorf("create round tripper: %w", err)
 		return
 	}
+	errorStream.Close()
+	defer s.conn.RemoveStreams(errorStream)
 
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", reqURL)
-
-	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	errorDone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, errorStream)
+		close(errorDone)
+	}()
 
-	pf, err := portforward.New(dialer, ports, stopCh, readyCh, io.Discard, io.Discard)
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := s.conn.CreateStream(headers)
 	if err != nil {
-		errorCh <- fmt.Errorf("create port forward: %w", err)
 		return
 	}
+	defer s.conn.RemoveStreams(dataStream)
 
-	err = pf.ForwardPorts()
-	if err != nil {
-		errorCh <- fmt.Errorf("forward ports: %w", err)
+	localDone := make(chan struct{})
+	remoteDone := make(chan struct{})
+	go func() {
+		io.Copy(conn, dataStream)
+		close(remoteDone)
+	}()
+	go func() {
+		defer dataStream.Close()
+		if _, err := io.Copy(dataStream, conn); err != nil {
+			close(localDone)
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-localDone:
 	}
+
+	dataStream.Reset()
+	<-errorDone
 }
 
-func findAvailablePort(startPort int32, usedPorts map[int32]bool) (int32, error) {
-	if startPort < 40000 {
-		startPort = 40000
+func newPortForwardDialer(config *rest.Config, reqURL *url.URL, used *string) (httpstream.Dialer, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("create round tripper: %w", err)
 	}
+	spdyDialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", reqURL)
 
-	for port := startPort; port < 65535; port++ {
-		if usedPorts[port] {
-			continue
-		}
-
-		addr, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-		if err != nil {
-			continue
-		}
-		defer addr.Close()
-		return port, nil
+	websocketDialer, err := portforward.NewSPDYOverWebsocketDialer(reqURL, config)
+	if err != nil {
+		return nil, fmt.Errorf("create websocket dialer: %w", err)
 	}
 
-	return 0, fmt.Errorf("no available port found starting from %d", startPort)
+	return portforward.NewFallbackDialer(
+		&recordingDialer{dialer: websocketDialer, transport: PortForwardTransportWebSocket, used: used},
+		&recordingDialer{dialer: spdyDialer, transport: PortForwardTransportSPDY, used: used},
+		shouldFallbackToSPDY,
+	), nil
+}
+
+func shouldFallbackToSPDY(err error) bool {
+	return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+}
+
+type recordingDialer struct {
+	dialer    httpstream.Dialer
+	transport string
+	used      *string
+}
+
+func (d *recordingDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	conn, protocol, err := d.dialer.Dial(protocols...)
+	if err == nil {
+		*d.used = d.transport
+	}
+	return conn, protocol, err
 }
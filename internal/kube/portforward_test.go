@@ -0,0 +1,258 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+)
+
+type fakeConnection struct {
+	closed    chan bool
+	closeOnce sync.Once
+}
+
+func newFakeConnection() *fakeConnection {
+	return &fakeConnection{closed: make(chan bool)}
+}
+
+func (c *fakeConnection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	return nil, fmt.Errorf("streams are not supported by the fake connection")
+}
+
+func (c *fakeConnection) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeConnection) CloseChan() <-chan bool {
+	return c.closed
+}
+
+func (c *fakeConnection) SetIdleTimeout(timeout time.Duration) {}
+
+func (c *fakeConnection) RemoveStreams(streams ...httpstream.Stream) {}
+
+func (c *fakeConnection) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+type fakeDialer struct {
+	err   error
+	calls int
+}
+
+func (d *fakeDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, "", d.err
+	}
+	return newFakeConnection(), portforward.PortForwardProtocolV1Name, nil
+}
+
+func TestPortForwardDialerFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		websocketErr  error
+		spdyErr       error
+		wantTransport string
+		wantSPDYCalls int
+		wantErr       bool
+	}{
+		{name: "websocket succeeds", wantTransport: PortForwardTransportWebSocket},
+		{name: "upgrade failure falls back", websocketErr: &httpstream.UpgradeFailureError{Cause: errors.New("400 Bad Request")}, wantTransport: PortForwardTransportSPDY, wantSPDYCalls: 1},
+		{name: "other errors do not fall back", websocketErr: errors.New("connection refused"), wantSPDYCalls: 0, wantErr: true},
+		{name: "fallback failure is reported", websocketErr: &httpstream.UpgradeFailureError{Cause: errors.New("400 Bad Request")}, spdyErr: errors.New("forbidden"), wantSPDYCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var used string
+			websocket := &fakeDialer{err: tt.websocketErr}
+			spdy := &fakeDialer{err: tt.spdyErr}
+			dialer := portforward.NewFallbackDialer(
+				&recordingDialer{dialer: websocket, transport: PortForwardTransportWebSocket, used: &used},
+				&recordingDialer{dialer: spdy, transport: PortForwardTransportSPDY, used: &used},
+				shouldFallbackToSPDY,
+			)
+
+			conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			if used != tt.wantTransport {
+				t.Errorf("recorded transport %q, want %q", used, tt.wantTransport)
+			}
+			if websocket.calls != 1 || spdy.calls != tt.wantSPDYCalls {
+				t.Errorf("dial calls websocket=%d spdy=%d, want 1 and %d", websocket.calls, spdy.calls, tt.wantSPDYCalls)
+			}
+		})
+	}
+}
+
+type fakeAllocator struct {
+	released []int32
+	mu       sync.Mutex
+}
+
+func (a *fakeAllocator) Allocate(key string, exclude map[int32]bool) (int32, error) {
+	return 0, fmt.Errorf("allocation is not supported by the fake allocator")
+}
+
+func (a *fakeAllocator) Reserve(port int32) error {
+	return nil
+}
+
+func (a *fakeAllocator) Take(port int32) ([]net.Listener, error) {
+	return nil, nil
+}
+
+func (a *fakeAllocator) Release(port int32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.released = append(a.released, port)
+}
+
+func (a *fakeAllocator) releasedPorts() []int32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	released := slices.Clone(a.released)
+	slices.Sort(released)
+	return released
+}
+
+func newReadySession(p *portForwardClient, contextName, namespace, pod string) (*podSession, *fakeConnection) {
+	conn := newFakeConnection()
+	session := &podSession{
+		key:       buildPodSessionKey(contextName, namespace, pod),
+		conn:      conn,
+		transport: PortForwardTransportWebSocket,
+		ready:     make(chan struct{}),
+		forwards:  make(map[string]*PortForward),
+	}
+	close(session.ready)
+	p.sessions[session.key] = session
+	return session, conn
+}
+
+func TestPortForwardsShareOneSessionPerPod(t *testing.T) {
+	allocator := &fakeAllocator{}
+	p := NewPortForwardClient(allocator, nil).(*portForwardClient)
+	session, conn := newReadySession(p, "ctx", "ns", "api-0")
+	ctx := context.Background()
+
+	for _, ports := range [][2]int32{{40001, 8080}, {40002, 9090}} {
+		transport, err := p.StartPortForward(ctx, "ctx", "ns", "api-0", ports[0], ports[1], nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transport != PortForwardTransportWebSocket {
+			t.Errorf("transport = %q, want the session's %q", transport, PortForwardTransportWebSocket)
+		}
+	}
+	if len(p.sessions) != 1 || len(session.forwards) != 2 {
+		t.Fatalf("expected 2 forwards on 1 session, got %d forwards on %d sessions", len(session.forwards), len(p.sessions))
+	}
+
+	if _, err := p.StartPortForward(ctx, "ctx", "ns", "api-0", 40003, 8080, nil, nil); err == nil {
+		t.Error("expected a duplicate remote port to be rejected")
+	}
+
+	if err := p.StopPortForward(BuildPortForwardKey("ctx", "ns", "api-0", 8080)); err != nil {
+		t.Fatal(err)
+	}
+	if conn.isClosed() {
+		t.Fatal("session closed while another forward still uses it")
+	}
+
+	if err := p.StopPortForward(BuildPortForwardKey("ctx", "ns", "api-0", 9090)); err != nil {
+		t.Fatal(err)
+	}
+	if !conn.isClosed() {
+		t.Error("expected the session to close with its last forward")
+	}
+	if len(p.sessions) != 0 || len(p.forwards) != 0 {
+		t.Errorf("expected no sessions or forwards left, got %d and %d", len(p.sessions), len(p.forwards))
+	}
+	if got, want := allocator.releasedPorts(), []int32{40001, 40002, 40003}; !slices.Equal(got, want) {
+		t.Errorf("released ports %v, want %v", got, want)
+	}
+}
+
+func TestLostSessionReportsEveryForward(t *testing.T) {
+	allocator := &fakeAllocator{}
+	lost := make(chan LostForward, 2)
+	p := NewPortForwardClient(allocator, func(forward LostForward) { lost <- forward }).(*portForwardClient)
+	session, conn := newReadySession(p, "ctx", "ns", "api-0")
+
+	for _, ports := range [][2]int32{{40001, 8080}, {40002, 9090}} {
+		if _, err := p.StartPortForward(context.Background(), "ctx", "ns", "api-0", ports[0], ports[1], nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go p.watchSession(session)
+	conn.Close()
+
+	var remotePorts []int32
+	for range 2 {
+		select {
+		case forward := <-lost:
+			if forward.Pod != "api-0" || forward.Transport != PortForwardTransportWebSocket {
+				t.Errorf("unexpected lost forward %+v", forward)
+			}
+			remotePorts = append(remotePorts, forward.RemotePort)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for lost forwards")
+		}
+	}
+	slices.Sort(remotePorts)
+	if !slices.Equal(remotePorts, []int32{8080, 9090}) {
+		t.Errorf("lost remote ports %v, want [8080 9090]", remotePorts)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.sessions) != 0 || len(p.forwards) != 0 {
+		t.Errorf("expected no sessions or forwards left, got %d and %d", len(p.sessions), len(p.forwards))
+	}
+}
+
+func TestStartPortForwardCancelledWhileSessionDials(t *testing.T) {
+	allocator := &fakeAllocator{}
+	p := NewPortForwardClient(allocator, nil).(*portForwardClient)
+	session := &podSession{
+		key:      buildPodSessionKey("ctx", "ns", "api-0"),
+		ready:    make(chan struct{}),
+		forwards: make(map[string]*PortForward),
+	}
+	p.sessions[session.key] = session
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.StartPortForward(ctx, "ctx", "ns", "api-0", 40001, 8080, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("StartPortForward() error = %v, want %v", err, context.Canceled)
+	}
+	if len(p.forwards) != 0 || len(p.sessions) != 0 {
+		t.Errorf("expected the cancelled forward and its idle session to be dropped, got %d forwards and %d sessions", len(p.forwards), len(p.sessions))
+	}
+	if got := allocator.releasedPorts(); !slices.Equal(got, []int32{40001}) {
+		t.Errorf("released ports %v, want [40001]", got)
+	}
+}
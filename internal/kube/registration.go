@@ -0,0 +1,183 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	registrationWorkers    = 8
+	registrationJobTimeout = 30 * time.Second
+)
+
+const (
+	SkipReasonExternalName      = "ExternalName service"
+	SkipReasonNoClusterIP       = "no ClusterIP"
+	SkipReasonNoHTTPPort        = "no HTTP port"
+	SkipReasonNoPods            = "no pods"
+	SkipReasonForwardFailed     = "forward failed"
+	SkipReasonListFailed        = "listing services failed"
+	SkipReasonAlreadyRegistered = "already registered"
+)
+
+type RegistrationResult struct {
+	Namespace string
+	Service   string
+	DNSURL    string
+	Reason    string
+	Err       error
+}
+
+func (r RegistrationResult) Registered() bool {
+	return r.Reason == ""
+}
+
+type registrationError struct {
+	reason string
+	err    error
+}
+
+func (e *registrationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *registrationError) Unwrap() error {
+	return e.err
+}
+
+func skipReason(err error) string {
+	var regErr *registrationError
+	if errors.As(err, &regErr) {
+		return regErr.reason
+	}
+	return SkipReasonForwardFailed
+}
+
+func canceled(ctx context.Context) bool {
+	return ctx.Err() != nil
+}
+
+func pickBulkPort(svc *Service) (*ServicePort, string) {
+	if svc.IsExternalName() {
+		return nil, SkipReasonExternalName
+	}
+	if svc.ClusterIP == "" {
+		return nil, SkipReasonNoClusterIP
+	}
+
+	httpPort := PickHTTPPort(svc)
+	if httpPort == nil {
+		return nil, SkipReasonNoHTTPPort
+	}
+	return httpPort, ""
+}
+
+func (m *kubeAdapter) collectServices(ctx context.Context, contextName string, services []Service) ([]Service, []RegistrationResult, error) {
+	if len(services) > 0 {
+		return services, nil, nil
+	}
+
+	namespaces, err := m.namespaceClient.ListNonSystemNamespaces(ctx, contextName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []RegistrationResult
+	for _, ns := range namespaces {
+		nsServices, err := m.ListServices(ctx, ns, contextName)
+		if err != nil {
+			results = append(results, RegistrationResult{Namespace: ns, Reason: SkipReasonListFailed, Err: err})
+			continue
+		}
+		services = append(services, nsServices...)
+	}
+	return services, results, nil
+}
+
+func (m *kubeAdapter) registerServices(
+	ctx context.Context,
+	contextName string,
+	services []Service,
+	usedPorts map[int32]bool,
+	registered map[string]bool,
+	config *rest.Config,
+	clientset kubernetes.Interface,
+) ([]ServiceTunnel, []RegistrationResult, error) {
+	results := make([]RegistrationResult, len(services))
+	started := make([]*ServiceTunnel, len(services))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(registrationWorkers, len(services)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				jobCtx, cancel := context.WithTimeout(ctx, registrationJobTimeout)
+				tunnel, result := m.registerService(jobCtx, contextName, &services[i], maps.Clone(usedPorts), registered, config, clientset)
+				cancel()
+				results[i] = result
+				if result.Registered() {
+					started[i] = &tunnel
+				}
+			}
+		}()
+	}
+	for i := range services {
+		if canceled(ctx) {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var tunnels []ServiceTunnel
+	for _, tunnel := range started {
+		if tunnel != nil {
+			tunnels = append(tunnels, *tunnel)
+		}
+	}
+	if canceled(ctx) {
+		m.stopServiceTunnels(tunnels)
+		return nil, nil, ctx.Err()
+	}
+	return tunnels, results, nil
+}
+
+func (m *kubeAdapter) registerService(
+	ctx context.Context,
+	contextName string,
+	svc *Service,
+	usedPorts map[int32]bool,
+	registered map[string]bool,
+	config *rest.Config,
+	clientset kubernetes.Interface,
+) (ServiceTunnel, RegistrationResult) {
+	result := RegistrationResult{Namespace: svc.Namespace, Service: svc.Name}
+
+	httpPort, reason := pickBulkPort(svc)
+	if httpPort == nil {
+		result.Reason = reason
+		return ServiceTunnel{}, result
+	}
+	result.DNSURL = BuildServiceDNS(svc.Name, svc.Namespace, httpPort.Port)
+	if registered[result.DNSURL] {
+		result.Reason = SkipReasonAlreadyRegistered
+		return ServiceTunnel{}, result
+	}
+
+	tunnel, err := m.startServiceForwards(ctx, contextName, svc, httpPort, usedPorts, config, clientset)
+	if err != nil {
+		result.Reason = skipReason(err)
+		result.Err = err
+		return ServiceTunnel{}, result
+	}
+	return tunnel, result
+}
@@ -0,0 +1,94 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCanceled(t *testing.T) {
+	if canceled(context.Background()) {
+		t.Error("expected a live context not to be canceled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !canceled(ctx) {
+		t.Error("expected a canceled context to be canceled")
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if !canceled(ctx) {
+		t.Error("expected an expired context to count as canceled")
+	}
+}
+
+func TestRegisterServicesReportsSkipReasons(t *testing.T) {
+	services := []Service{
+		{Name: "db", Namespace: "default", Type: string(corev1.ServiceTypeExternalName), ExternalName: "db.example.com"},
+		{Name: "none", Namespace: "default"},
+		{Name: "cache", Namespace: "default", ClusterIP: "10.0.0.2", Ports: []ServicePort{{Name: "redis", Port: 6379}}},
+		{Name: "api", Namespace: "default", ClusterIP: "10.0.0.3", Ports: []ServicePort{{Name: "http", Port: 80}}},
+	}
+	registered := map[string]bool{"api.default": true}
+
+	m := &kubeAdapter{}
+	tunnels, results, err := m.registerServices(context.Background(), "ctx", services, map[int32]bool{}, registered, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tunnels) != 0 {
+		t.Errorf("expected no tunnels, got %+v", tunnels)
+	}
+
+	want := []RegistrationResult{
+		{Namespace: "default", Service: "db", Reason: SkipReasonExternalName},
+		{Namespace: "default", Service: "none", Reason: SkipReasonNoClusterIP},
+		{Namespace: "default", Service: "cache", Reason: SkipReasonNoHTTPPort},
+		{Namespace: "default", Service: "api", DNSURL: "api.default", Reason: SkipReasonAlreadyRegistered},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+}
+
+func TestRegisterServicesStopsWhenContextEnds(t *testing.T) {
+	services := []Service{{Name: "api", Namespace: "default", ClusterIP: "10.0.0.3", Ports: []ServicePort{{Name: "http", Port: 80}}}}
+
+	for _, makeContext := range []func() (context.Context, context.CancelFunc){
+		func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		},
+		func() (context.Context, context.CancelFunc) {
+			return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		},
+	} {
+		ctx, cancel := makeContext()
+		m := &kubeAdapter{}
+		tunnels, results, err := m.registerServices(ctx, "ctx", services, map[int32]bool{}, map[string]bool{}, nil, nil)
+		cancel()
+
+		if !errors.Is(err, ctx.Err()) {
+			t.Errorf("registerServices() error = %v, want %v", err, ctx.Err())
+		}
+		if tunnels != nil || results != nil {
+			t.Errorf("expected no tunnels or results, got %+v and %+v", tunnels, results)
+		}
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	if got := skipReason(&registrationError{reason: SkipReasonNoPods, err: errors.New("no pods")}); got != SkipReasonNoPods {
+		t.Errorf("skipReason() = %q, want %q", got, SkipReasonNoPods)
+	}
+	if got := skipReason(errors.New("dial failed")); got != SkipReasonForwardFailed {
+		t.Errorf("skipReason() = %q, want %q", got, SkipReasonForwardFailed)
+	}
+}
@@ -0,0 +1,434 @@
+package kube
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	relayManagedByLabel = "app.kubernetes.io/managed-by"
+	relayComponentLabel = "app.kubernetes.io/component"
+	relayOwnerLabel     = "kube-service-tunnel/owner"
+	relaySessionLabel   = "kube-service-tunnel/session"
+	relayHeartbeatKey   = "kube-service-tunnel/heartbeat"
+	relayManagedBy      = "kube-service-tunnel"
+	relayComponent      = "relay"
+	relayReadyTimeout   = 60 * time.Second
+	relayMaxLifetime    = int64(12 * 60 * 60)
+	relayHeartbeat      = time.Minute
+	relayStaleAfter     = 5 * time.Minute
+)
+
+type RelayOptions struct {
+	Image     string
+	Namespace string
+	CPU       string
+	Memory    string
+	Labels    map[string]string
+}
+
+type relayPod struct {
+	context   string
+	namespace string
+	pod       string
+}
+
+func DefaultRelayOptions() RelayOptions {
+	return RelayOptions{
+		Image:  "alpine/socat:latest",
+		CPU:    "100m",
+		Memory: "64Mi",
+	}
+}
+
+func newRelaySession() (string, string) {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	session := hex.EncodeToString(buf)
+
+	hostname, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		username = sudoUser
+	}
+	sum := sha256.Sum256([]byte(hostname + "/" + username))
+	return session, hex.EncodeToString(sum[:8])
+}
+
+func (m *kubeAdapter) StartRelay(ctx context.Context, contextName, namespace, targetHost string, targetPort, localPort int32, usedPorts map[int32]bool) (ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, relayReadyTimeout+10*time.Second)
+	defer cancel()
+
+	if m.options.Relay.Namespace != "" {
+		namespace = m.options.Relay.Namespace
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	if err := m.CollectOrphanRelays(ctx, contextName, []string{namespace}); err != nil {
+		m.reportRelayError(err)
+	}
+
+	pod, err := m.buildRelayPod(namespace, targetHost, targetPort)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+
+	if localPort == 0 {
+		localPort, err = m.ports.Allocate("", usedPorts)
+		if err != nil {
+			return ServiceTunnel{}, fmt.Errorf("find available port: %w", err)
+		}
+	} else if usedPorts[localPort] {
+		return ServiceTunnel{}, fmt.Errorf("local port %d is already used by another tunnel", localPort)
+	} else if err := m.ports.Reserve(localPort); err != nil {
+		return ServiceTunnel{}, fmt.Errorf("relay needs local port %d: %w", localPort, err)
+	}
+
+	created, err := clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		m.ports.Release(localPort)
+		return ServiceTunnel{}, fmt.Errorf("create relay pod in namespace %s: %w", namespace, err)
+	}
+
+	m.relayMu.Lock()
+	m.relays[created.Name] = relayPod{context: contextName, namespace: namespace, pod: created.Name}
+	if !m.relayBeating {
+		m.relayBeating = true
+		go m.runRelayHeartbeat()
+	}
+	m.relayMu.Unlock()
+
+	if err := waitForRelayPod(ctx, clientset, namespace, created.Name); err != nil {
+		m.ports.Release(localPort)
+		m.DeleteRelay(contextName, namespace, created.Name)
+		return ServiceTunnel{}, err
+	}
+
+	transport, err := m.portForwardClient.StartPortForward(ctx, contextName, namespace, created.Name, localPort, targetPort, config, clientset)
+	if err != nil {
+		m.DeleteRelay(contextName, namespace, created.Name)
+		return ServiceTunnel{}, fmt.Errorf("start port forward: %w", err)
+	}
+	usedPorts[localPort] = true
+
+	return ServiceTunnel{
+		Context:    contextName,
+		Namespace:  namespace,
+		Pod:        created.Name,
+		LocalPort:  localPort,
+		RemotePort: targetPort,
+		Transport:  transport,
+	}, nil
+}
+
+func (m *kubeAdapter) DeleteRelay(contextName, namespace, pod string) error {
+	m.relayMu.Lock()
+	delete(m.relays, pod)
+	m.relayMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	return deleteRelayPod(ctx, clientset, namespace, pod)
+}
+
+func (m *kubeAdapter) DeleteAllRelays() {
+	m.relayMu.Lock()
+	relays := make([]relayPod, 0, len(m.relays))
+	for _, relay := range m.relays {
+		relays = append(relays, relay)
+	}
+	m.relayMu.Unlock()
+
+	for _, relay := range relays {
+		m.DeleteRelay(relay.context, relay.namespace, relay.pod)
+	}
+}
+
+func (m *kubeAdapter) runRelayHeartbeat() {
+	ticker := time.NewTicker(relayHeartbeat)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.relayMu.Lock()
+		if len(m.relays) == 0 {
+			m.relayBeating = false
+			m.relayMu.Unlock()
+			return
+		}
+		relays := make([]relayPod, 0, len(m.relays))
+		for _, relay := range m.relays {
+			relays = append(relays, relay)
+		}
+		m.relayMu.Unlock()
+
+		for _, relay := range relays {
+			if err := m.touchRelay(relay); err != nil {
+				m.reportRelayError(err)
+			}
+		}
+	}
+}
+
+func (m *kubeAdapter) touchRelay(relay relayPod) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, relay.context)
+	if err != nil {
+		return fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{relayHeartbeatKey: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Pods(relay.namespace).Patch(ctx, relay.pod, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("update relay pod %s/%s heartbeat: %w", relay.namespace, relay.pod, err)
+	}
+	return nil
+}
+
+func (m *kubeAdapter) CollectOrphanRelays(ctx context.Context, contextName string, namespaces []string) error {
+	namespaces = m.uncollectedRelayNamespaces(contextName, namespaces)
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		m.resetRelayCollected(contextName, namespaces)
+		return fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		m.resetRelayCollected(contextName, namespaces)
+		return fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	var errs []error
+	for _, namespace := range namespaces {
+		if err := m.collectOrphanRelays(ctx, contextName, namespace, clientset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *kubeAdapter) uncollectedRelayNamespaces(contextName string, namespaces []string) []string {
+	if m.options.Relay.Namespace != "" {
+		namespaces = []string{m.options.Relay.Namespace}
+	}
+
+	m.relayMu.Lock()
+	defer m.relayMu.Unlock()
+
+	var pending []string
+	for _, namespace := range namespaces {
+		key := contextName + "/" + namespace
+		if namespace == "" || m.relayCollected[key] {
+			continue
+		}
+		m.relayCollected[key] = true
+		pending = append(pending, namespace)
+	}
+	return pending
+}
+
+func (m *kubeAdapter) resetRelayCollected(contextName string, namespaces []string) {
+	m.relayMu.Lock()
+	defer m.relayMu.Unlock()
+	for _, namespace := range namespaces {
+		delete(m.relayCollected, contextName+"/"+namespace)
+	}
+}
+
+func (m *kubeAdapter) collectOrphanRelays(ctx context.Context, contextName, namespace string, clientset kubernetes.Interface) error {
+	selector := fmt.Sprintf("%s=%s,%s=%s,%s=%s,%s!=%s",
+		relayManagedByLabel, relayManagedBy,
+		relayComponentLabel, relayComponent,
+		relayOwnerLabel, m.relayOwner,
+		relaySessionLabel, m.relaySession,
+	)
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		m.resetRelayCollected(contextName, []string{namespace})
+		return fmt.Errorf("list relay pods in namespace %s: %w", namespace, err)
+	}
+
+	var errs []error
+	for _, pod := range pods.Items {
+		if isStaleRelay(pod) {
+			if err := deleteRelayPod(ctx, clientset, pod.Namespace, pod.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *kubeAdapter) reportRelayError(err error) {
+	if m.options.OnRelayError != nil {
+		m.options.OnRelayError(err)
+	}
+}
+
+func isStaleRelay(pod corev1.Pod) bool {
+	lastSeen := pod.CreationTimestamp.Time
+	if heartbeat, err := time.Parse(time.RFC3339, pod.Annotations[relayHeartbeatKey]); err == nil {
+		lastSeen = heartbeat
+	}
+	return time.Since(lastSeen) > relayStaleAfter
+}
+
+func (m *kubeAdapter) buildRelayPod(namespace, targetHost string, targetPort int32) (*corev1.Pod, error) {
+	options := m.options.Relay
+
+	requirements := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	if options.CPU != "" {
+		cpu, err := resource.ParseQuantity(options.CPU)
+		if err != nil {
+			return nil, fmt.Errorf("parse relay cpu %q: %w", options.CPU, err)
+		}
+		requirements.Requests[corev1.ResourceCPU] = cpu
+		requirements.Limits[corev1.ResourceCPU] = cpu
+	}
+	if options.Memory != "" {
+		memory, err := resource.ParseQuantity(options.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("parse relay memory %q: %w", options.Memory, err)
+		}
+		requirements.Requests[corev1.ResourceMemory] = memory
+		requirements.Limits[corev1.ResourceMemory] = memory
+	}
+
+	labels := make(map[string]string, len(options.Labels)+4)
+	for k, v := range options.Labels {
+		labels[k] = v
+	}
+	labels[relayManagedByLabel] = relayManagedBy
+	labels[relayComponentLabel] = relayComponent
+	labels[relayOwnerLabel] = m.relayOwner
+	labels[relaySessionLabel] = m.relaySession
+
+	image := options.Image
+	if image == "" {
+		image = DefaultRelayOptions().Image
+	}
+
+	deadline := relayMaxLifetime
+	port := strconv.Itoa(int(targetPort))
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kst-relay-",
+			Namespace:    namespace,
+			Labels:       labels,
+			Annotations: map[string]string{
+				"kube-service-tunnel/target": net.JoinHostPort(targetHost, port),
+				relayHeartbeatKey:            time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &deadline,
+			Containers: []corev1.Container{{
+				Name:  "relay",
+				Image: image,
+				Args: []string{
+					"TCP-LISTEN:" + port + ",fork,reuseaddr",
+					"TCP:" + net.JoinHostPort(targetHost, port),
+				},
+				Ports:     []corev1.ContainerPort{{ContainerPort: targetPort, Protocol: corev1.ProtocolTCP}},
+				Resources: requirements,
+			}},
+		},
+	}, nil
+}
+
+func waitForRelayPod(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, relayReadyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			if isPodReady(*pod) {
+				return nil
+			}
+			if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+				return fmt.Errorf("relay pod %s/%s exited: %s", namespace, name, pod.Status.Phase)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("relay pod %s/%s did not become ready within %s", namespace, name, relayReadyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func deleteRelayPod(ctx context.Context, clientset kubernetes.Interface, namespace, name string) error {
+	grace := int64(0)
+	err := clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete relay pod %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
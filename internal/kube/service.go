@@ -3,6 +3,7 @@ package kube
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,19 +12,29 @@ import (
 )
 
 type ServicePort struct {
-	Name       string
-	Port       int32
-	TargetPort int32
-	Protocol   string
+	Name        string
+	Port        int32
+	TargetPort  int32
+	Protocol    string
+	AppProtocol string
 }
 
 type Service struct {
-	Name      string
-	Namespace string
-	ClusterIP string
-	Type      string
-	Ports     []ServicePort
-	Selector  map[string]string
+	Name         string
+	Namespace    string
+	ClusterIP    string
+	Type         string
+	Ports        []ServicePort
+	Selector     map[string]string
+	ExternalName string
+}
+
+func (s *Service) IsHeadless() bool {
+	return s.ClusterIP == corev1.ClusterIPNone
+}
+
+func (s *Service) IsExternalName() bool {
+	return s.Type == string(corev1.ServiceTypeExternalName)
 }
 
 type ServiceInterface interface {
@@ -58,11 +69,7 @@ func (s *serviceClient) ListServices(ctx context.Context, namespace, contextName
 
 	var result []Service
 	for _, svc := range services.Items {
-		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer || svc.Spec.Type == corev1.ServiceTypeNodePort {
-			continue
-		}
-
-		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != "None" {
+		if svc.Spec.ClusterIP != "" || svc.Spec.Type == corev1.ServiceTypeExternalName {
 			serviceType := string(svc.Spec.Type)
 			if serviceType == "" {
 				serviceType = string(corev1.ServiceTypeClusterIP)
@@ -74,11 +81,16 @@ func (s *serviceClient) ListServices(ctx context.Context, namespace, contextName
 				if port.TargetPort.Type == intstr.Int {
 					targetPort = port.TargetPort.IntVal
 				}
+				appProtocol := ""
+				if port.AppProtocol != nil {
+					appProtocol = *port.AppProtocol
+				}
 				ports = append(ports, ServicePort{
-					Name:       port.Name,
-					Port:       port.Port,
-					TargetPort: targetPort,
-					Protocol:   string(port.Protocol),
+					Name:        port.Name,
+					Port:        port.Port,
+					TargetPort:  targetPort,
+					Protocol:    string(port.Protocol),
+					AppProtocol: appProtocol,
 				})
 			}
 
@@ -90,12 +102,13 @@ func (s *serviceClient) ListServices(ctx context.Context, namespace, contextName
 			}
 
 			result = append(result, Service{
-				Name:      svc.Name,
-				Namespace: svc.Namespace,
-				ClusterIP: svc.Spec.ClusterIP,
-				Type:      serviceType,
-				Ports:     ports,
-				Selector:  selector,
+				Name:         svc.Name,
+				Namespace:    svc.Namespace,
+				ClusterIP:    svc.Spec.ClusterIP,
+				Type:         serviceType,
+				Ports:        ports,
+				Selector:     selector,
+				ExternalName: svc.Spec.ExternalName,
 			})
 		}
 	}
@@ -109,9 +122,41 @@ func PickHTTPPort(svc *Service) *ServicePort {
 			return &svc.Ports[i]
 		}
 	}
+	for i := range svc.Ports {
+		if DetectPortProtocol(&svc.Ports[i]) != "" {
+			return &svc.Ports[i]
+		}
+	}
+	if svc.IsHeadless() && len(svc.Ports) > 0 {
+		return &svc.Ports[0]
+	}
+	return nil
+}
+
+func findServicePort(svc *Service, port int32) *ServicePort {
+	for i := range svc.Ports {
+		if svc.Ports[i].Port == port {
+			return &svc.Ports[i]
+		}
+	}
 	return nil
 }
 
+func DetectPortProtocol(port *ServicePort) string {
+	appProtocol := strings.ToLower(port.AppProtocol)
+	name := strings.ToLower(port.Name)
+
+	switch {
+	case appProtocol == "kubernetes.io/h2c" || appProtocol == "h2c" || name == "h2c" || strings.HasPrefix(name, "h2c-"):
+		return "h2c"
+	case appProtocol == "grpc" || name == "grpc" || strings.HasPrefix(name, "grpc-"):
+		return "grpc"
+	case appProtocol == "http" || name == "http" || strings.HasPrefix(name, "http-"):
+		return "http1"
+	}
+	return ""
+}
+
 func BuildServiceDNS(serviceName, namespace string, port int32) string {
 	host := fmt.Sprintf("%s.%s", serviceName, namespace)
 	if port == 80 {
@@ -120,6 +165,10 @@ func BuildServiceDNS(serviceName, namespace string, port int32) string {
 	return fmt.Sprintf("%s:%d.%s", serviceName, port, namespace)
 }
 
+func BuildPodDNS(podName, serviceName, namespace string, port int32) string {
+	return podName + "." + BuildServiceDNS(serviceName, namespace, port)
+}
+
 func isHTTPPort(port int32) bool {
 	httpPorts := []int32{80, 8080, 3000, 8000, 9000}
 	for _, p := range httpPorts {
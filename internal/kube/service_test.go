@@ -0,0 +1,26 @@
+package kube
+
+import "testing"
+
+func TestDetectPortProtocol(t *testing.T) {
+	tests := []struct {
+		port ServicePort
+		want string
+	}{
+		{port: ServicePort{Name: "h2c"}, want: "h2c"},
+		{port: ServicePort{Name: "h2c-api"}, want: "h2c"},
+		{port: ServicePort{AppProtocol: "kubernetes.io/h2c"}, want: "h2c"},
+		{port: ServicePort{Name: "grpc-web"}, want: "grpc"},
+		{port: ServicePort{Name: "api", AppProtocol: "GRPC"}, want: "grpc"},
+		{port: ServicePort{Name: "http-metrics"}, want: "http1"},
+		{port: ServicePort{Name: "h2c", AppProtocol: "http"}, want: "h2c"},
+		{port: ServicePort{Name: "web"}, want: ""},
+		{port: ServicePort{Name: "grpcish"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := DetectPortProtocol(&tt.port); got != tt.want {
+			t.Errorf("DetectPortProtocol(name=%q, appProtocol=%q) = %q, want %q", tt.port.Name, tt.port.AppProtocol, got, tt.want)
+		}
+	}
+}
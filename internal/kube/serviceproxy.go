@@ -0,0 +1,125 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func (m *kubeAdapter) PlanServiceProxy(ctx context.Context, contextName, serviceName, namespace string, servicePort int32) (ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	svc, err := m.findService(ctx, contextName, namespace, serviceName)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+
+	if svc.IsExternalName() {
+		tunnel := ServiceTunnel{
+			Context:      contextName,
+			Namespace:    namespace,
+			DNSURL:       BuildServiceDNS(serviceName, namespace, 80),
+			ServiceName:  serviceName,
+			ExternalName: svc.ExternalName,
+		}
+		if port := findServicePort(svc, servicePort); port != nil {
+			tunnel.ServicePort = port.Port
+		} else if len(svc.Ports) > 0 {
+			tunnel.ServicePort = svc.Ports[0].Port
+		}
+		return tunnel, nil
+	}
+
+	var httpPort *ServicePort
+	if servicePort != 0 {
+		httpPort = findServicePort(svc, servicePort)
+		if httpPort == nil {
+			return ServiceTunnel{}, fmt.Errorf("port %d not found for service %s/%s", servicePort, namespace, serviceName)
+		}
+	} else {
+		httpPort = PickHTTPPort(svc)
+		if httpPort == nil {
+			return ServiceTunnel{}, fmt.Errorf("no HTTP port found for service %s/%s", namespace, serviceName)
+		}
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return ServiceTunnel{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	return buildServiceProxyTunnel(config, contextName, svc, httpPort)
+}
+
+func (m *kubeAdapter) PlanServiceProxies(ctx context.Context, contextName string, services []Service) ([]ServiceTunnel, []RegistrationResult, error) {
+	planned, results, err := m.PlanServiceTunnels(ctx, contextName, services)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	tunnels := make([]ServiceTunnel, 0, len(planned))
+	for _, tunnel := range planned {
+		proxyURL, err := buildServiceProxyURL(config, tunnel.Namespace, tunnel.ServiceName, tunnel.ServicePort)
+		if err != nil {
+			return nil, nil, err
+		}
+		tunnel.ProxyURL = proxyURL
+		tunnel.Protocol = ""
+		tunnels = append(tunnels, tunnel)
+	}
+	return tunnels, results, nil
+}
+
+func (m *kubeAdapter) ServiceProxyTransport(contextName string) (http.RoundTripper, error) {
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("create API server transport: %w", err)
+	}
+	return transport, nil
+}
+
+func buildServiceProxyTunnel(config *rest.Config, contextName string, svc *Service, httpPort *ServicePort) (ServiceTunnel, error) {
+	proxyURL, err := buildServiceProxyURL(config, svc.Namespace, svc.Name, httpPort.Port)
+	if err != nil {
+		return ServiceTunnel{}, err
+	}
+
+	return ServiceTunnel{
+		Context:     contextName,
+		Namespace:   svc.Namespace,
+		DNSURL:      BuildServiceDNS(svc.Name, svc.Namespace, httpPort.Port),
+		ServiceName: svc.Name,
+		ServicePort: httpPort.Port,
+		ProxyURL:    proxyURL,
+	}, nil
+}
+
+func buildServiceProxyURL(config *rest.Config, namespace, serviceName string, port int32) (string, error) {
+	host := config.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	base, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("parse API server address %s: %w", config.Host, err)
+	}
+
+	return base.JoinPath("api", "v1", "namespaces", namespace, "services", serviceName+":"+strconv.Itoa(int(port)), "proxy").String(), nil
+}
@@ -0,0 +1,55 @@
+package kube
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestBuildServiceProxyURL(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "https://10.0.0.1:6443", want: "https://10.0.0.1:6443/api/v1/namespaces/default/services/api:8080/proxy"},
+		{host: "10.0.0.1:6443", want: "https://10.0.0.1:6443/api/v1/namespaces/default/services/api:8080/proxy"},
+		{host: "https://rancher.example.com/k8s/clusters/c-1", want: "https://rancher.example.com/k8s/clusters/c-1/api/v1/namespaces/default/services/api:8080/proxy"},
+		{host: "http://127.0.0.1:8001/", want: "http://127.0.0.1:8001/api/v1/namespaces/default/services/api:8080/proxy"},
+	}
+
+	for _, tt := range tests {
+		got, err := buildServiceProxyURL(&rest.Config{Host: tt.host}, "default", "api", 8080)
+		if err != nil {
+			t.Errorf("buildServiceProxyURL(%q) error: %v", tt.host, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("buildServiceProxyURL(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+
+	if _, err := buildServiceProxyURL(&rest.Config{Host: "https://bad host"}, "default", "api", 80); err == nil {
+		t.Error("expected an error for an unparsable API server address")
+	}
+}
+
+func TestBuildServiceProxyTunnel(t *testing.T) {
+	svc := &Service{Name: "api", Namespace: "shop"}
+	tunnel, err := buildServiceProxyTunnel(&rest.Config{Host: "https://k8s:6443"}, "prod", svc, &ServicePort{Name: "http", Port: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ServiceTunnel{
+		Context:     "prod",
+		Namespace:   "shop",
+		DNSURL:      "api.shop",
+		ServiceName: "api",
+		ServicePort: 80,
+		ProxyURL:    "https://k8s:6443/api/v1/namespaces/shop/services/api:80/proxy",
+	}
+	if !reflect.DeepEqual(tunnel, want) {
+		t.Errorf("buildServiceProxyTunnel() = %+v, want %+v", tunnel, want)
+	}
+}
@@ -0,0 +1,264 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+	WorkloadKindPod         = "Pod"
+)
+
+type Workload struct {
+	Kind          string
+	Name          string
+	Namespace     string
+	Replicas      int32
+	ReadyReplicas int32
+	Selector      string
+}
+
+type WorkloadInterface interface {
+	ListWorkloads(ctx context.Context, namespace, contextName string) ([]Workload, error)
+	GetWorkload(ctx context.Context, namespace, contextName, kind, name string) (Workload, error)
+}
+
+type workloadClient struct {
+	kubeconfigPath string
+}
+
+func NewWorkloadClient(kubeconfigPath string) (*workloadClient, error) {
+	return &workloadClient{
+		kubeconfigPath: kubeconfigPath,
+	}, nil
+}
+
+func (w *workloadClient) ListWorkloads(ctx context.Context, namespace, contextName string) ([]Workload, error) {
+	config, err := loadKubeconfigWithContext(w.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list deployments in namespace %s: %w", namespace, err)
+	}
+
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list statefulsets in namespace %s: %w", namespace, err)
+	}
+
+	result := make([]Workload, 0, len(deployments.Items)+len(statefulSets.Items))
+	for _, deployment := range deployments.Items {
+		result = append(result, convertDeployment(deployment))
+	}
+	for _, statefulSet := range statefulSets.Items {
+		result = append(result, convertStatefulSet(statefulSet))
+	}
+
+	return result, nil
+}
+
+func (w *workloadClient) GetWorkload(ctx context.Context, namespace, contextName, kind, name string) (Workload, error) {
+	config, err := loadKubeconfigWithContext(w.kubeconfigPath, contextName)
+	if err != nil {
+		return Workload{}, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return Workload{}, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	switch kind {
+	case WorkloadKindDeployment:
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return Workload{}, fmt.Errorf("get deployment %s/%s: %w", namespace, name, err)
+		}
+		return convertDeployment(*deployment), nil
+	case WorkloadKindStatefulSet:
+		statefulSet, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return Workload{}, fmt.Errorf("get statefulset %s/%s: %w", namespace, name, err)
+		}
+		return convertStatefulSet(*statefulSet), nil
+	}
+	return Workload{}, fmt.Errorf("unsupported workload kind: %s", kind)
+}
+
+func convertDeployment(deployment appsv1.Deployment) Workload {
+	workload := Workload{
+		Kind:          WorkloadKindDeployment,
+		Name:          deployment.Name,
+		Namespace:     deployment.Namespace,
+		Replicas:      1,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+	}
+	if deployment.Spec.Replicas != nil {
+		workload.Replicas = *deployment.Spec.Replicas
+	}
+	workload.Selector = workloadSelector(deployment.Spec.Selector)
+	return workload
+}
+
+func convertStatefulSet(statefulSet appsv1.StatefulSet) Workload {
+	workload := Workload{
+		Kind:          WorkloadKindStatefulSet,
+		Name:          statefulSet.Name,
+		Namespace:     statefulSet.Namespace,
+		Replicas:      1,
+		ReadyReplicas: statefulSet.Status.ReadyReplicas,
+	}
+	if statefulSet.Spec.Replicas != nil {
+		workload.Replicas = *statefulSet.Spec.Replicas
+	}
+	workload.Selector = workloadSelector(statefulSet.Spec.Selector)
+	return workload
+}
+
+func workloadSelector(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return ""
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+func BuildWorkloadDNS(kind, name, namespace string, port int32) string {
+	return BuildServiceDNS(name, strings.ToLower(kind)+"."+namespace, port)
+}
+
+func PickWorkloadPod(ctx context.Context, podClient PodInterface, contextName string, workload Workload) (Pod, error) {
+	pods, err := podClient.FindSelectedPods(ctx, workload.Namespace, contextName, workload.Selector)
+	if err != nil {
+		return Pod{}, fmt.Errorf("find pods for %s %s/%s: %w", workload.Kind, workload.Namespace, workload.Name, err)
+	}
+
+	for _, pod := range pods {
+		if pod.Ready {
+			return pod, nil
+		}
+	}
+	return Pod{}, fmt.Errorf("no ready pods for %s %s/%s", workload.Kind, workload.Namespace, workload.Name)
+}
+
+func (m *kubeAdapter) ListWorkloads(ctx context.Context, namespace, contextName string) ([]Workload, error) {
+	return m.workloadClient.ListWorkloads(ctx, namespace, contextName)
+}
+
+func (m *kubeAdapter) ListPods(ctx context.Context, namespace, contextName string) ([]Pod, error) {
+	return m.podClient.ListPods(ctx, namespace, contextName)
+}
+
+func (m *kubeAdapter) RegisterWorkloadPortForwards(ctx context.Context, contextName, namespace, kind, name string, usedPorts map[int32]bool) ([]ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	workload, err := m.workloadClient.GetWorkload(ctx, namespace, contextName, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	if workload.Selector == "" {
+		return nil, fmt.Errorf("%s %s/%s has no usable pod selector", kind, namespace, name)
+	}
+
+	pod, err := PickWorkloadPod(ctx, m.podClient, contextName, workload)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.startContainerForwards(ctx, contextName, kind, name, pod, usedPorts)
+}
+
+func (m *kubeAdapter) RegisterPodPortForwards(ctx context.Context, contextName, namespace, podName string, usedPorts map[int32]bool) ([]ServiceTunnel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	pod, err := m.podClient.GetPod(ctx, namespace, contextName, podName)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status != string(corev1.PodRunning) {
+		return nil, fmt.Errorf("pod %s/%s is %s, not Running", namespace, podName, pod.Status)
+	}
+
+	return m.startContainerForwards(ctx, contextName, WorkloadKindPod, podName, pod, usedPorts)
+}
+
+func (m *kubeAdapter) startContainerForwards(ctx context.Context, contextName, kind, name string, pod Pod, usedPorts map[int32]bool) ([]ServiceTunnel, error) {
+	config, err := loadKubeconfigWithContext(m.kubeconfigPath, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+
+	var tunnels []ServiceTunnel
+	seen := make(map[int32]bool)
+	var lastErr error
+	for _, port := range pod.Ports {
+		if port.Protocol != "" && port.Protocol != string(corev1.ProtocolTCP) {
+			continue
+		}
+		if seen[port.ContainerPort] {
+			continue
+		}
+		seen[port.ContainerPort] = true
+
+		tunnel := ServiceTunnel{
+			Context:     contextName,
+			Namespace:   pod.Namespace,
+			DNSURL:      BuildWorkloadDNS(kind, name, pod.Namespace, port.ContainerPort),
+			ServiceName: name,
+			ServicePort: port.ContainerPort,
+			Kind:        kind,
+			Protocol:    DetectPortProtocol(&ServicePort{Name: port.Name, Port: port.ContainerPort}),
+		}
+		forward, err := m.startPodForward(ctx, tunnel, ServiceBackend{Pod: pod.Name, Port: port.ContainerPort}, buildLocalPortKey(tunnel), usedPorts, config, clientset)
+		if err != nil {
+			if canceled(ctx) {
+				m.stopServiceTunnels(tunnels)
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+
+		tunnel.Pod = forward.Pod
+		tunnel.LocalPort = forward.LocalPort
+		tunnel.RemotePort = forward.RemotePort
+		tunnel.Transport = forward.Transport
+		tunnels = append(tunnels, tunnel)
+	}
+
+	if len(tunnels) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("pod %s/%s exposes no TCP container ports", pod.Namespace, pod.Name)
+	}
+
+	return tunnels, nil
+}
@@ -0,0 +1,207 @@
+package ports
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/config"
+)
+
+const (
+	DefaultMin int32 = 40000
+	DefaultMax int32 = 65534
+)
+
+type AllocatorInterface interface {
+	Allocate(key string, exclude map[int32]bool) (int32, error)
+	Reserve(port int32) error
+	Take(port int32) ([]net.Listener, error)
+	Release(port int32)
+}
+
+type allocator struct {
+	min      int32
+	max      int32
+	path     string
+	mappings map[string]int32
+	reserved map[int32][]net.Listener
+	onError  func(error)
+	mu       sync.Mutex
+}
+
+func NewAllocator(min, max int32, path string, onError func(error)) (AllocatorInterface, error) {
+	if min == 0 {
+		min = DefaultMin
+	}
+	if max == 0 {
+		max = DefaultMax
+	}
+	if min < 1 || max > 65535 || min > max {
+		return nil, fmt.Errorf("invalid port range %d-%d", min, max)
+	}
+
+	a := &allocator{
+		min:      min,
+		max:      max,
+		path:     path,
+		mappings: make(map[string]int32),
+		reserved: make(map[int32][]net.Listener),
+		onError:  onError,
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *allocator) Allocate(key string, exclude map[int32]bool) (int32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	mapped, ok := a.mappings[key]
+	if ok && key != "" && a.available(mapped, exclude) {
+		if listeners, err := listen(mapped); err == nil {
+			a.reserved[mapped] = listeners
+			return mapped, nil
+		}
+	}
+	remap := key != "" && (!ok || mapped < a.min || mapped > a.max)
+
+	claimed := make(map[int32]bool, len(a.mappings))
+	for mappedKey, port := range a.mappings {
+		if mappedKey != key {
+			claimed[port] = true
+		}
+	}
+
+	for _, skipClaimed := range []bool{true, false} {
+		for port := a.min; port <= a.max; port++ {
+			if !a.available(port, exclude) || (skipClaimed && claimed[port]) {
+				continue
+			}
+			listeners, err := listen(port)
+			if err != nil {
+				continue
+			}
+			a.reserved[port] = listeners
+			if remap {
+				a.mappings[key] = port
+				if err := a.save(); err != nil && a.onError != nil {
+					go a.onError(err)
+				}
+			}
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no available port in range %d-%d", a.min, a.max)
+}
+
+func (a *allocator) Reserve(port int32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, reserved := a.reserved[port]; reserved {
+		return fmt.Errorf("port %d is already used by another tunnel", port)
+	}
+	listeners, err := listen(port)
+	if err != nil {
+		return err
+	}
+	a.reserved[port] = listeners
+	return nil
+}
+
+func (a *allocator) Take(port int32) ([]net.Listener, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	listeners, reserved := a.reserved[port]
+	if !reserved {
+		bound, err := listen(port)
+		if err != nil {
+			return nil, err
+		}
+		listeners = bound
+	} else if len(listeners) == 0 {
+		return nil, fmt.Errorf("port %d is already used by another tunnel", port)
+	}
+	a.reserved[port] = []net.Listener{}
+	return listeners, nil
+}
+
+func (a *allocator) Release(port int32) {
+	a.mu.Lock()
+	listeners, reserved := a.reserved[port]
+	delete(a.reserved, port)
+	a.mu.Unlock()
+
+	if reserved {
+		closeListeners(listeners)
+	}
+}
+
+func (a *allocator) available(port int32, exclude map[int32]bool) bool {
+	if port < a.min || port > a.max || exclude[port] {
+		return false
+	}
+	_, reserved := a.reserved[port]
+	return !reserved
+}
+
+func (a *allocator) load() error {
+	if a.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read port mappings: %w", err)
+	}
+	if err := json.Unmarshal(data, &a.mappings); err != nil {
+		return fmt.Errorf("parse port mappings %s: %w", a.path, err)
+	}
+	return nil
+}
+
+func (a *allocator) save() error {
+	if a.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(a.mappings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode port mappings: %w", err)
+	}
+	if err := config.WriteFile(a.path, data, 0644); err != nil {
+		return fmt.Errorf("save port mappings: %w", err)
+	}
+	return nil
+}
+
+func listen(port int32) ([]net.Listener, error) {
+	portText := strconv.Itoa(int(port))
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", portText))
+	if err != nil {
+		return nil, fmt.Errorf("listen on local port %d: %w", port, err)
+	}
+
+	listeners := []net.Listener{listener}
+	if listener6, err := net.Listen("tcp", net.JoinHostPort("::1", portText)); err == nil {
+		listeners = append(listeners, listener6)
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
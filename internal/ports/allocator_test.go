@@ -0,0 +1,233 @@
+package ports
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freePortRange(t *testing.T, size int32) (int32, int32) {
+	t.Helper()
+
+	for attempt := 0; attempt < 20; attempt++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := int32(listener.Addr().(*net.TCPAddr).Port)
+		listener.Close()
+		if start+size > 65535 {
+			continue
+		}
+
+		free := true
+		for port := start; port < start+size; port++ {
+			probe, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+			if err != nil {
+				free = false
+				break
+			}
+			probe.Close()
+		}
+		if free {
+			return start, start + size - 1
+		}
+	}
+	t.Skip("no free port range available")
+	return 0, 0
+}
+
+func readMappings(t *testing.T, path string) map[string]int32 {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings := make(map[string]int32)
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		t.Fatal(err)
+	}
+	return mappings
+}
+
+func TestAllocatorPersistsMappings(t *testing.T) {
+	min, max := freePortRange(t, 4)
+	path := filepath.Join(t.TempDir(), "ports.json")
+
+	a, err := NewAllocator(min, max, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiPort, err := a.Allocate("ctx/default/api:80", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webPort, err := a.Allocate("ctx/default/web:80", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiPort == webPort {
+		t.Fatalf("expected distinct ports, both got %d", apiPort)
+	}
+	a.Release(apiPort)
+	a.Release(webPort)
+
+	if got := readMappings(t, path); got["ctx/default/api:80"] != apiPort || got["ctx/default/web:80"] != webPort {
+		t.Errorf("saved mappings %v, want api=%d web=%d", got, apiPort, webPort)
+	}
+
+	reloaded, err := NewAllocator(min, max, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := reloaded.Allocate("ctx/default/web:80", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Release(port)
+	if port != webPort {
+		t.Errorf("reloaded allocator gave web port %d, want its saved port %d", port, webPort)
+	}
+}
+
+func TestAllocatorKeepsMappingWhenPortIsBusy(t *testing.T) {
+	min, max := freePortRange(t, 4)
+	path := filepath.Join(t.TempDir(), "ports.json")
+
+	a, err := NewAllocator(min, max, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := a.Allocate("api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := a.Allocate("api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release(other)
+	a.Release(mapped)
+	if other == mapped {
+		t.Fatalf("expected a different port while %d is reserved", mapped)
+	}
+
+	if got := readMappings(t, path)["api"]; got != mapped {
+		t.Errorf("saved mapping %d, want the original %d", got, mapped)
+	}
+
+	again, err := a.Allocate("api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release(again)
+	if again != mapped {
+		t.Errorf("expected the mapped port %d once it is free again, got %d", mapped, again)
+	}
+}
+
+func TestAllocatorSkipsPortsClaimedByOtherKeys(t *testing.T) {
+	min, max := freePortRange(t, 4)
+	path := filepath.Join(t.TempDir(), "ports.json")
+	data, err := json.Marshal(map[string]int32{"claimed": min})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAllocator(min, max, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := a.Allocate("fresh", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release(port)
+	if port == min {
+		t.Errorf("allocated %d, which is mapped to another key", port)
+	}
+}
+
+func TestAllocatorRejectsCorruptMappings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ports.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAllocator(0, 0, path, nil); err == nil {
+		t.Error("expected an error for a corrupt port mappings file")
+	}
+	if _, err := NewAllocator(0, 0, filepath.Join(t.TempDir(), "missing.json"), nil); err != nil {
+		t.Errorf("expected a missing mappings file to be ignored, got %v", err)
+	}
+}
+
+func TestAllocatorReportsSaveErrors(t *testing.T) {
+	min, max := freePortRange(t, 2)
+	path := filepath.Join(t.TempDir(), "missing-dir", "ports.json")
+	errs := make(chan error, 1)
+
+	a, err := NewAllocator(min, max, path, func(err error) { errs <- err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := a.Allocate("api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release(port)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("unexpected save error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the save error to be reported")
+	}
+}
+
+func TestReserveAndTake(t *testing.T) {
+	min, max := freePortRange(t, 2)
+	a, err := NewAllocator(min, max, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Reserve(min); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reserve(min); err == nil {
+		t.Error("expected reserving a reserved port to fail")
+	}
+
+	listeners, err := a.Take(min)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) == 0 {
+		t.Fatal("expected the reserved listeners to be handed over")
+	}
+	if _, err := a.Take(min); err == nil {
+		t.Error("expected taking a port twice to fail")
+	}
+	for _, listener := range listeners {
+		listener.Close()
+	}
+
+	a.Release(min)
+	if err := a.Reserve(min); err != nil {
+		t.Errorf("expected a released port to be reservable again: %v", err)
+	}
+	a.Release(min)
+}
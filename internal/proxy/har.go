@@ -0,0 +1,471 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	harRedacted      = "[REDACTED]"
+	harFlushInterval = 2 * time.Second
+)
+
+type HAROptions struct {
+	Dir           string
+	MaxBodyBytes  int64
+	MaxEntries    int
+	RedactHeaders []string
+	CaptureHosts  []string
+}
+
+type harLog struct {
+	Log harLogBody `json:"log"`
+}
+
+type harLogBody struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harRecorder struct {
+	path       string
+	redact     map[string]bool
+	maxEntries int
+	file       *os.File
+	offset     int64
+	written    int
+	pending    []harEntry
+	dropped    int
+	dirty      bool
+	lastErr    error
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	mu         sync.Mutex
+}
+
+func newHARRecorder(options HAROptions, host string) (*harRecorder, error) {
+	if err := os.MkdirAll(options.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("create HAR directory %s: %w", options.Dir, err)
+	}
+
+	name := fmt.Sprintf("%s-%s.har", sanitizeFileName(host), time.Now().Format("20060102-150405"))
+	redact := make(map[string]bool, len(options.RedactHeaders))
+	for _, header := range options.RedactHeaders {
+		redact[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+
+	header, err := json.Marshal(harLog{
+		Log: harLogBody{
+			Version: "1.2",
+			Creator: harCreator{Name: "kube-service-tunnel", Version: "1.0"},
+			Entries: []harEntry{},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode HAR: %w", err)
+	}
+	header = bytes.TrimSuffix(header, []byte("]}}"))
+
+	path := filepath.Join(options.Dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create HAR file: %w", err)
+	}
+
+	recorder := &harRecorder{
+		path:       path,
+		redact:     redact,
+		maxEntries: options.MaxEntries,
+		file:       file,
+		offset:     int64(len(header)),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if _, err := file.WriteAt(header, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write HAR file: %w", err)
+	}
+	if err := recorder.writeTrailerLocked(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	go recorder.run()
+	return recorder, nil
+}
+
+func (h *harRecorder) add(exchange Exchange) {
+	h.mu.Lock()
+	full := h.maxEntries > 0 && h.written+len(h.pending) >= h.maxEntries
+	if full {
+		h.dropped++
+		h.dirty = true
+	}
+	h.mu.Unlock()
+	if full {
+		return
+	}
+
+	entry := h.buildEntry(exchange)
+
+	h.mu.Lock()
+	h.pending = append(h.pending, entry)
+	h.mu.Unlock()
+}
+
+func (h *harRecorder) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(harFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.flush()
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func (h *harRecorder) close() error {
+	h.closeOnce.Do(func() {
+		close(h.stop)
+		<-h.done
+
+		h.flush()
+		h.mu.Lock()
+		if err := h.file.Close(); err != nil && h.lastErr == nil {
+			h.lastErr = fmt.Errorf("write HAR file: %w", err)
+		}
+		h.mu.Unlock()
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastErr
+}
+
+func (h *harRecorder) flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.pending) == 0 && !h.dirty {
+		return
+	}
+	if err := h.flushLocked(); err != nil {
+		h.lastErr = err
+	}
+}
+
+func (h *harRecorder) flushLocked() error {
+	var buf bytes.Buffer
+	for _, entry := range h.pending {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode HAR: %w", err)
+		}
+		if h.written > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+		buf.Write(data)
+		h.written++
+	}
+	h.pending = nil
+
+	if _, err := h.file.WriteAt(buf.Bytes(), h.offset); err != nil {
+		return fmt.Errorf("write HAR file: %w", err)
+	}
+	h.offset += int64(buf.Len())
+	return h.writeTrailerLocked()
+}
+
+func (h *harRecorder) writeTrailerLocked() error {
+	trailer := "\n]}}\n"
+	if h.dropped > 0 {
+		comment, err := json.Marshal(fmt.Sprintf("capture limit of %d entries reached, %d later requests were not recorded", h.maxEntries, h.dropped))
+		if err != nil {
+			return fmt.Errorf("encode HAR: %w", err)
+		}
+		trailer = fmt.Sprintf("\n],\"comment\":%s}}\n", comment)
+	}
+
+	if _, err := h.file.WriteAt([]byte(trailer), h.offset); err != nil {
+		return fmt.Errorf("write HAR file: %w", err)
+	}
+	if err := h.file.Truncate(h.offset + int64(len(trailer))); err != nil {
+		return fmt.Errorf("write HAR file: %w", err)
+	}
+	h.dirty = false
+	return nil
+}
+
+func encodeBody(mimeType string, body []byte) (string, string) {
+	if isTextContent(mimeType) && utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func (h *harRecorder) buildEntry(exchange Exchange) harEntry {
+	requestURL := fmt.Sprintf("%s://%s%s", exchange.Scheme, exchange.Host, exchange.URL)
+	latency := float64(exchange.Latency.Microseconds()) / 1000
+
+	request := harRequest{
+		Method:      exchange.Method,
+		URL:         requestURL,
+		HTTPVersion: exchange.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     h.headers(exchange.RequestHeaders),
+		QueryString: queryString(exchange.URL),
+		HeadersSize: -1,
+		BodySize:    exchange.RequestSize,
+	}
+	if exchange.RequestSize > 0 {
+		request.PostData = &harPostData{
+			MimeType: exchange.RequestHeaders.Get("Content-Type"),
+		}
+		request.PostData.Text, request.PostData.Encoding = encodeBody(request.PostData.MimeType, exchange.RequestBody)
+		if exchange.RequestTruncated {
+			request.PostData.Comment = "truncated"
+		}
+	}
+
+	content := harContent{
+		Size:     exchange.ResponseSize,
+		MimeType: exchange.ResponseHeaders.Get("Content-Type"),
+	}
+	if len(exchange.ResponseBody) > 0 {
+		content.Text, content.Encoding = encodeBody(content.MimeType, exchange.ResponseBody)
+	}
+	if exchange.ResponseTruncated {
+		content.Comment = "truncated"
+	}
+
+	return harEntry{
+		StartedDateTime: exchange.StartedAt.Format(time.RFC3339Nano),
+		Time:            latency,
+		Request:         request,
+		Response: harResponse{
+			Status:      exchange.Status,
+			StatusText:  http.StatusText(exchange.Status),
+			HTTPVersion: exchange.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     h.headers(exchange.ResponseHeaders),
+			Content:     content,
+			RedirectURL: exchange.ResponseHeaders.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    exchange.ResponseSize,
+		},
+		Timings: harTimings{
+			Wait: latency,
+		},
+	}
+}
+
+func (h *harRecorder) headers(headers http.Header) []harNameValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []harNameValue{}
+	for _, name := range names {
+		for _, value := range headers[name] {
+			if h.redact[http.CanonicalHeaderKey(name)] {
+				value = harRedacted
+			}
+			result = append(result, harNameValue{Name: name, Value: value})
+		}
+	}
+	return result
+}
+
+func queryString(requestURI string) []harNameValue {
+	result := []harNameValue{}
+
+	parsed, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return result
+	}
+
+	values := parsed.Query()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range values[key] {
+			result = append(result, harNameValue{Name: key, Value: value})
+		}
+	}
+	return result
+}
+
+func isTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func matchesCaptureHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, host); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *proxyAdapter) StartCapture(host string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.startCaptureLocked(host)
+}
+
+func (p *proxyAdapter) startCaptureLocked(host string) (string, error) {
+	_, routed := p.routes[host]
+	_, lazy := p.lazy[host]
+	if !routed && !lazy {
+		return "", fmt.Errorf("no route found for host: %s", host)
+	}
+	if recorder, ok := p.captures[host]; ok {
+		return recorder.path, nil
+	}
+
+	recorder, err := newHARRecorder(p.options.HAR, host)
+	if err != nil {
+		return "", err
+	}
+	p.captures[host] = recorder
+	return recorder.path, nil
+}
+
+func (p *proxyAdapter) startAutoCaptureLocked(host string) {
+	if !matchesCaptureHost(p.options.HAR.CaptureHosts, host) {
+		return
+	}
+	if _, err := p.startCaptureLocked(host); err != nil && p.options.OnCaptureError != nil {
+		go p.options.OnCaptureError(host, fmt.Errorf("start HAR capture: %w", err))
+	}
+}
+
+func (p *proxyAdapter) StopCapture(host string) (string, error) {
+	p.mu.Lock()
+	recorder, ok := p.captures[host]
+	delete(p.captures, host)
+	p.mu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("capture not running for host: %s", host)
+	}
+	return recorder.path, recorder.close()
+}
+
+func (p *proxyAdapter) IsCapturing(host string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.captures[host]
+	return ok
+}
@@ -0,0 +1,368 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Exchange struct {
+	ID                 uint64
+	Host               string
+	Scheme             string
+	Method             string
+	URL                string
+	Proto              string
+	Status             int
+	StartedAt          time.Time
+	Latency            time.Duration
+	RequestSize        int64
+	ResponseSize       int64
+	RequestHeaders     http.Header
+	ResponseHeaders    http.Header
+	RequestBody        []byte
+	ResponseBody       []byte
+	RequestTruncated   bool
+	ResponseTruncated  bool
+	RequestBodyCapture bool
+	replayHeaders      http.Header
+}
+
+type InspectorOptions struct {
+	Capacity       int
+	CaptureHeaders bool
+	MaxBodyBytes   int64
+}
+
+type Options struct {
+	Inspector      InspectorOptions
+	HAR            HAROptions
+	Rules          []RouteRule
+	OnCaptureError func(host string, err error)
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Inspector: InspectorOptions{
+			Capacity: 200,
+		},
+		HAR: HAROptions{
+			Dir:           filepath.Join(os.TempDir(), "kube-service-tunnel-har"),
+			MaxBodyBytes:  1 << 20,
+			MaxEntries:    10000,
+			RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		},
+	}
+}
+
+type exchangeLog struct {
+	entries []Exchange
+	next    int
+	count   int
+	mu      sync.RWMutex
+}
+
+func newExchangeLog(capacity int) *exchangeLog {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &exchangeLog{
+		entries: make([]Exchange, capacity),
+	}
+}
+
+func (l *exchangeLog) add(exchange Exchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = exchange
+	l.next = (l.next + 1) % len(l.entries)
+	if l.count < len(l.entries) {
+		l.count++
+	}
+}
+
+func (l *exchangeLog) list() []Exchange {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make([]Exchange, 0, l.count)
+	for i := 0; i < l.count; i++ {
+		index := (l.next - 1 - i + len(l.entries)) % len(l.entries)
+		result = append(result, l.entries[index])
+	}
+	return result
+}
+
+func (l *exchangeLog) find(id uint64) (Exchange, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for i := 0; i < l.count; i++ {
+		if l.entries[i].ID == id {
+			return l.entries[i], true
+		}
+	}
+	return Exchange{}, false
+}
+
+type inspector struct {
+	options    InspectorOptions
+	harOptions HAROptions
+	nextID     atomic.Uint64
+}
+
+type capturingBody struct {
+	body      io.ReadCloser
+	buf       bytes.Buffer
+	limit     int64
+	size      int64
+	truncated bool
+}
+
+func (c *capturingBody) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if n > 0 {
+		c.size += int64(n)
+		c.capture(p[:n])
+	}
+	return n, err
+}
+
+func (c *capturingBody) Close() error {
+	return c.body.Close()
+}
+
+func (c *capturingBody) capture(data []byte) {
+	remaining := c.limit - int64(c.buf.Len())
+	if remaining <= 0 {
+		if len(data) > 0 {
+			c.truncated = true
+		}
+		return
+	}
+	if int64(len(data)) > remaining {
+		data = data[:remaining]
+		c.truncated = true
+	}
+	c.buf.Write(data)
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	body        capturingBody
+	status      int
+	wroteHeader bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	if n > 0 {
+		w.body.size += int64(n)
+		w.body.capture(p[:n])
+	}
+	return n, err
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (i *inspector) serve(log *exchangeLog, har *harRecorder, host string, handler http.Handler, w http.ResponseWriter, r *http.Request) {
+	startedAt := time.Now()
+
+	captureHeaders := i.options.CaptureHeaders || har != nil
+	maxBodyBytes := i.options.MaxBodyBytes
+	if har != nil {
+		maxBodyBytes = max(maxBodyBytes, i.harOptions.MaxBodyBytes)
+	}
+
+	requestBody := &capturingBody{limit: maxBodyBytes}
+	if r.Body != nil && r.Body != http.NoBody {
+		requestBody.body = r.Body
+		r.Body = requestBody
+	}
+
+	requestHeaders := r.Header.Clone()
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	recorder := &recordingWriter{
+		ResponseWriter: w,
+		body:           capturingBody{limit: maxBodyBytes},
+		status:         http.StatusOK,
+	}
+
+	handler.ServeHTTP(recorder, r)
+
+	exchange := Exchange{
+		ID:                 i.nextID.Add(1),
+		Host:               host,
+		Scheme:             scheme,
+		Method:             r.Method,
+		URL:                r.URL.RequestURI(),
+		Proto:              r.Proto,
+		Status:             recorder.status,
+		StartedAt:          startedAt,
+		Latency:            time.Since(startedAt),
+		RequestSize:        requestBody.size,
+		ResponseSize:       recorder.body.size,
+		RequestTruncated:   requestBody.truncated,
+		ResponseTruncated:  recorder.body.truncated,
+		RequestBodyCapture: maxBodyBytes > 0,
+		replayHeaders:      requestHeaders,
+	}
+	if !i.options.CaptureHeaders {
+		exchange.replayHeaders = withoutHeaders(requestHeaders, i.harOptions.RedactHeaders)
+	}
+	if captureHeaders {
+		exchange.RequestHeaders = requestHeaders
+		exchange.ResponseHeaders = w.Header().Clone()
+	}
+	if maxBodyBytes > 0 {
+		exchange.RequestBody = requestBody.buf.Bytes()
+		exchange.ResponseBody = recorder.body.buf.Bytes()
+	}
+
+	if har != nil {
+		har.add(exchange)
+	}
+
+	log.add(i.limitExchange(exchange))
+}
+
+func (i *inspector) limitExchange(exchange Exchange) Exchange {
+	if !i.options.CaptureHeaders {
+		exchange.RequestHeaders = nil
+		exchange.ResponseHeaders = nil
+	}
+
+	limit := i.options.MaxBodyBytes
+	exchange.RequestBodyCapture = limit > 0
+	exchange.RequestBody, exchange.RequestTruncated = limitBody(exchange.RequestBody, limit, exchange.RequestTruncated)
+	exchange.ResponseBody, exchange.ResponseTruncated = limitBody(exchange.ResponseBody, limit, exchange.ResponseTruncated)
+	return exchange
+}
+
+func withoutHeaders(headers http.Header, names []string) http.Header {
+	result := headers.Clone()
+	for _, name := range names {
+		result.Del(strings.TrimSpace(name))
+	}
+	return result
+}
+
+func limitBody(body []byte, limit int64, truncated bool) ([]byte, bool) {
+	if limit <= 0 {
+		return nil, truncated || len(body) > 0
+	}
+	if int64(len(body)) <= limit {
+		return body, truncated
+	}
+	return bytes.Clone(body[:limit]), true
+}
+
+func (p *proxyAdapter) Exchanges(host string) []Exchange {
+	p.mu.RLock()
+	log, ok := p.logs[host]
+	p.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return log.list()
+}
+
+func (p *proxyAdapter) ReplayExchange(host string, id uint64) (int, error) {
+	p.mu.RLock()
+	log, ok := p.logs[host]
+	p.mu.RUnlock()
+
+	if !ok {
+		return 0, fmt.Errorf("no route found for host: %s", host)
+	}
+
+	exchange, ok := log.find(id)
+	if !ok {
+		return 0, fmt.Errorf("request %d not found for host: %s", id, host)
+	}
+	if exchange.replayHeaders == nil {
+		return 0, fmt.Errorf("request headers were not captured, cannot replay")
+	}
+	if exchange.RequestSize > 0 && (!exchange.RequestBodyCapture || exchange.RequestTruncated) {
+		return 0, fmt.Errorf("request body was not fully captured, cannot replay")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	scheme := exchange.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(ctx, exchange.Method, fmt.Sprintf("%s://%s%s", scheme, host, exchange.URL), bytes.NewReader(exchange.RequestBody))
+	if err != nil {
+		return 0, fmt.Errorf("build replay request: %w", err)
+	}
+	req.Header = exchange.replayHeaders.Clone()
+	req.Host = host
+	req.RemoteAddr = "127.0.0.1:0"
+	req.RequestURI = exchange.URL
+	if major, minor, ok := http.ParseHTTPVersion(exchange.Proto); ok {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = exchange.Proto, major, minor
+	}
+	if scheme == "https" {
+		req.TLS = &tls.ConnectionState{ServerName: host}
+	}
+
+	w := &replayWriter{header: make(http.Header)}
+	p.HandleProxyRequest(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.status, nil
+}
+
+type replayWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *replayWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *replayWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *replayWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(p), nil
+}
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type lazyRoute struct {
+	activate func(host string) error
+	mu       sync.Mutex
+	lastSeen atomic.Int64
+	inFlight atomic.Int32
+}
+
+func (l *lazyRoute) touch() {
+	l.lastSeen.Store(time.Now().UnixNano())
+}
+
+func (p *proxyAdapter) AddLazyRoute(host string, activate func(host string) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lazy[host] = &lazyRoute{activate: activate}
+	if _, ok := p.logs[host]; !ok {
+		p.logs[host] = newExchangeLog(p.options.Inspector.Capacity)
+	}
+	p.startAutoCaptureLocked(host)
+}
+
+func (p *proxyAdapter) DeactivateIdleRoute(host string, idleFor time.Duration, deactivate func(host string)) bool {
+	p.mu.RLock()
+	lazy, ok := p.lazy[host]
+	p.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+
+	p.mu.Lock()
+	if _, active := p.routes[host]; !active || p.lazy[host] != lazy {
+		p.mu.Unlock()
+		return false
+	}
+	if lazy.inFlight.Load() > 0 || time.Since(time.Unix(0, lazy.lastSeen.Load())) < idleFor {
+		p.mu.Unlock()
+		return false
+	}
+	delete(p.routes, host)
+	p.mu.Unlock()
+
+	deactivate(host)
+	return true
+}
+
+func (p *proxyAdapter) activateLazy(host string, lazy *lazyRoute) (*routeTable, error) {
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+
+	p.mu.RLock()
+	table, ok := p.routes[host]
+	p.mu.RUnlock()
+	if ok {
+		return table, nil
+	}
+
+	if err := lazy.activate(host); err != nil {
+		return nil, err
+	}
+	lazy.touch()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	table, ok = p.routes[host]
+	if !ok {
+		return nil, fmt.Errorf("tunnel for host %s was removed", host)
+	}
+	return table, nil
+}
+
+func (p *proxyAdapter) acquireLazy(host string) (int32, func(), error) {
+	p.mu.RLock()
+	lazy := p.lazy[host]
+	table, exists := p.routes[host]
+	if lazy != nil {
+		lazy.inFlight.Add(1)
+		lazy.touch()
+	}
+	p.mu.RUnlock()
+
+	if lazy == nil {
+		return 0, nil, fmt.Errorf("no route found for host: %s", host)
+	}
+	release := func() {
+		lazy.touch()
+		lazy.inFlight.Add(-1)
+	}
+
+	if !exists {
+		var err error
+		table, err = p.activateLazy(host, lazy)
+		if err != nil {
+			release()
+			return 0, nil, fmt.Errorf("start tunnel for host %s: %w", host, err)
+		}
+	}
+
+	p.mu.RLock()
+	root, ok := table.get(PathMatch{Path: "/"}.Key())
+	p.mu.RUnlock()
+	if !ok || len(root.backends) == 0 || root.backends[0].localPort == 0 {
+		release()
+		return 0, nil, fmt.Errorf("no local port for host: %s", host)
+	}
+	return root.backends[0].localPort, release, nil
+}
+
+type lazyTransport struct {
+	adapter *proxyAdapter
+	host    string
+	base    http.RoundTripper
+}
+
+func (t *lazyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	port, release, err := t.adapter.acquireLazy(t.host)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Host = fmt.Sprintf("localhost:%d", port)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: sync.OnceFunc(release)}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
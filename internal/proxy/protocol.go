@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+type Protocol string
+
+const (
+	ProtocolHTTP1 Protocol = "http1"
+	ProtocolH2C   Protocol = "h2c"
+	ProtocolGRPC  Protocol = "grpc"
+)
+
+var Protocols = []Protocol{ProtocolHTTP1, ProtocolH2C, ProtocolGRPC}
+
+func ParseProtocol(value string) (Protocol, error) {
+	if value == "" {
+		return ProtocolHTTP1, nil
+	}
+	for _, protocol := range Protocols {
+		if strings.EqualFold(value, string(protocol)) {
+			return protocol, nil
+		}
+	}
+	return "", fmt.Errorf("unknown protocol: %s", value)
+}
+
+func (p Protocol) Next() Protocol {
+	for i, protocol := range Protocols {
+		if protocol == p {
+			return Protocols[(i+1)%len(Protocols)]
+		}
+	}
+	return ProtocolHTTP1
+}
+
+func newH2CTransport() *http.Transport {
+	transport := newTransport()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return transport
+}
+
+func serverProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+func (p *proxyAdapter) transportFor(protocol Protocol) *http.Transport {
+	if protocol == ProtocolH2C || protocol == ProtocolGRPC {
+		return p.h2cTransport
+	}
+	return p.transport
+}
+
+func configureProtocol(proxy *httputil.ReverseProxy, protocol Protocol) {
+	if protocol != ProtocolGRPC {
+		return
+	}
+
+	proxy.FlushInterval = -1
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "14")
+		w.Header().Set("Grpc-Message", fmt.Sprintf("upstream unavailable: %v", err))
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestParseProtocol(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Protocol
+		wantErr bool
+	}{
+		{value: "", want: ProtocolHTTP1},
+		{value: "http1", want: ProtocolHTTP1},
+		{value: "H2C", want: ProtocolH2C},
+		{value: "grpc", want: ProtocolGRPC},
+		{value: "http3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseProtocol(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProtocol(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseProtocol(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestProtocolNextCycles(t *testing.T) {
+	protocol := ProtocolHTTP1
+	for _, want := range []Protocol{ProtocolH2C, ProtocolGRPC, ProtocolHTTP1} {
+		protocol = protocol.Next()
+		if protocol != want {
+			t.Fatalf("Next() = %q, want %q", protocol, want)
+		}
+	}
+	if got := Protocol("unknown").Next(); got != ProtocolHTTP1 {
+		t.Errorf("Next() of an unknown protocol = %q, want %q", got, ProtocolHTTP1)
+	}
+}
+
+func newProtocolBackend(t *testing.T, handler http.HandlerFunc) int32 {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.Protocols = serverProtocols()
+	server.Start()
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int32(port)
+}
+
+func TestH2CRouteReachesBackendOverHTTP2(t *testing.T) {
+	port := newProtocolBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend-Proto", r.Proto)
+	})
+
+	p := NewProxyAdapter(nil, Options{}).(*proxyAdapter)
+	defer p.h2cTransport.CloseIdleConnections()
+	p.AddRoute("grpc.ns", port)
+	if err := p.SetRouteProtocol("grpc.ns", ProtocolH2C); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.HandleProxyRequest(rec, httptest.NewRequest(http.MethodGet, "http://grpc.ns/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Backend-Proto"); got != "HTTP/2.0" {
+		t.Errorf("backend saw %q, want HTTP/2.0", got)
+	}
+}
+
+func TestGRPCRouteReportsUnavailableBackend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := int32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	p := NewProxyAdapter(nil, Options{}).(*proxyAdapter)
+	p.AddRoute("grpc.ns", port)
+	if err := p.SetRouteProtocol("grpc.ns", ProtocolGRPC); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://grpc.ns/pkg.Service/Method", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	rec := httptest.NewRecorder()
+	p.HandleProxyRequest(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status %d, want 200 with a gRPC status", rec.Code)
+	}
+	if got := rec.Header().Get("Grpc-Status"); got != "14" {
+		t.Errorf("Grpc-Status = %q, want 14", got)
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -8,27 +9,111 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/byoungmin/kube-service-tunnel/internal/cert"
 )
 
 type ProxyAdapterInterface interface {
 	StartIfNotRunning(port int32) error
+	StartTLSIfNotRunning(port int32) error
 	Stop() error
 	AddRoute(host string, localPort int32)
 	AddRoutes(routes map[string]int32)
+	AddUpstreamRoute(host string, upstream *Upstream)
+	AddPathRoute(host, prefix string, localPort int32, protocol Protocol)
+	RemovePathRoute(host, prefix string)
+	AddMatchRoute(host string, match PathMatch, backends []Backend) error
+	RemoveMatchRoute(host string, match PathMatch)
 	RemoveRoute(host string)
+	SetRouteProtocol(host string, protocol Protocol) error
+	Exchanges(host string) []Exchange
+	ReplayExchange(host string, id uint64) (int, error)
+	StartCapture(host string) (string, error)
+	StopCapture(host string) (string, error)
+	IsCapturing(host string) bool
+	AddLazyRoute(host string, activate func(host string) error)
+	DeactivateIdleRoute(host string, idleFor time.Duration, deactivate func(host string)) bool
+}
+
+type backend struct {
+	localPort int32
+	protocol  Protocol
+	weight    int32
+	upstream  *Upstream
+	lazy      string
+	proxy     *httputil.ReverseProxy
+}
+
+type route struct {
+	key         string
+	match       PathMatch
+	headers     []headerMatcher
+	backends    []*backend
+	totalWeight int32
+	rule        *RouteRule
+}
+
+func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.rule != nil && r.rule.handlePreflight(w, req) {
+		return
+	}
+
+	target := r.pick()
+	if target == nil {
+		http.Error(w, "no backend available for route", http.StatusServiceUnavailable)
+		return
+	}
+	target.proxy.ServeHTTP(w, req)
 }
 
 type proxyAdapter struct {
-	server   *http.Server
-	listener net.Listener
-	routes   map[string]int32
-	mu       sync.RWMutex
-	port     int32
+	server       *http.Server
+	listener     net.Listener
+	tlsServer    *http.Server
+	tlsListener  net.Listener
+	authority    cert.AuthorityInterface
+	transport    *http.Transport
+	h2cTransport *http.Transport
+	routes       map[string]*routeTable
+	logs         map[string]*exchangeLog
+	captures     map[string]*harRecorder
+	lazy         map[string]*lazyRoute
+	inspector    *inspector
+	options      Options
+	mu           sync.RWMutex
+	port         int32
+	tlsPort      int32
 }
 
-func NewProxyAdapter() ProxyAdapterInterface {
+func NewProxyAdapter(authority cert.AuthorityInterface, options Options) ProxyAdapterInterface {
 	return &proxyAdapter{
-		routes: make(map[string]int32),
+		authority:    authority,
+		options:      options,
+		inspector:    &inspector{options: options.Inspector, harOptions: options.HAR},
+		routes:       make(map[string]*routeTable),
+		logs:         make(map[string]*exchangeLog),
+		captures:     make(map[string]*harRecorder),
+		lazy:         make(map[string]*lazyRoute),
+		transport:    newTransport(),
+		h2cTransport: newH2CTransport(),
+	}
+}
+
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          512,
+		MaxIdleConnsPerHost:   64,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
 	}
 }
 
@@ -49,16 +134,18 @@ func (p *proxyAdapter) start(port int32) error {
 	}
 
 	p.port = port
-	p.routes = make(map[string]int32)
+	p.routes = make(map[string]*routeTable)
+	p.logs = make(map[string]*exchangeLog)
+	p.captures = make(map[string]*harRecorder)
+	p.lazy = make(map[string]*lazyRoute)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		HandleProxyRequest(p.routes, &p.mu, w, r)
-	})
+	mux.HandleFunc("/", p.HandleProxyRequest)
 
 	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+		Addr:      fmt.Sprintf(":%d", port),
+		Handler:   mux,
+		Protocols: serverProtocols(),
 	}
 
 	listener, err := net.Listen("tcp", p.server.Addr)
@@ -77,29 +164,113 @@ func (p *proxyAdapter) start(port int32) error {
 	return nil
 }
 
-func (p *proxyAdapter) Stop() error {
+func (p *proxyAdapter) StartTLSIfNotRunning(port int32) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.server == nil {
+	if p.tlsListener != nil {
 		return nil
 	}
 
+	if p.authority == nil {
+		return fmt.Errorf("no certificate authority configured")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.HandleProxyRequest)
+
+	p.tlsPort = port
+	p.tlsServer = &http.Server{
+		Addr:      fmt.Sprintf(":%d", port),
+		Handler:   mux,
+		Protocols: serverProtocols(),
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: p.getCertificate,
+		},
+	}
+
+	listener, err := net.Listen("tcp", p.tlsServer.Addr)
+	if err != nil {
+		p.tlsServer = nil
+		return fmt.Errorf("listen on port %d: %w", port, err)
+	}
+
+	p.tlsListener = listener
+	server := p.tlsServer
+
+	go func() {
+		if err := server.ServeTLS(listener, "", ""); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("TLS proxy server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func (p *proxyAdapter) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := hello.ServerName
+	if host == "" {
+		return nil, fmt.Errorf("client did not send a server name")
+	}
+
+	p.mu.RLock()
+	_, exists := p.routes[host]
+	_, lazy := p.lazy[host]
+	p.mu.RUnlock()
+
+	if !exists && !lazy {
+		return nil, fmt.Errorf("no route found for host: %s", host)
+	}
+
+	return p.authority.IssueCertificate(host)
+}
+
+func (p *proxyAdapter) Stop() error {
+	p.mu.Lock()
+	captures, err := p.stopLocked()
+	p.mu.Unlock()
+
+	for _, recorder := range captures {
+		recorder.close()
+	}
+	return err
+}
+
+func (p *proxyAdapter) stopLocked() (map[string]*harRecorder, error) {
+	if p.tlsServer != nil {
+		if err := p.tlsServer.Close(); err != nil {
+			return nil, fmt.Errorf("close TLS server: %w", err)
+		}
+		p.tlsListener = nil
+		p.tlsServer = nil
+	}
+
+	if p.server == nil {
+		return nil, nil
+	}
+
 	if p.listener != nil {
 		if err := p.listener.Close(); err != nil {
-			return fmt.Errorf("close listener: %w", err)
+			return nil, fmt.Errorf("close listener: %w", err)
 		}
 	}
 
 	if err := p.server.Close(); err != nil {
-		return fmt.Errorf("close server: %w", err)
+		return nil, fmt.Errorf("close server: %w", err)
 	}
 
+	captures := p.captures
 	p.listener = nil
 	p.server = nil
-	p.routes = make(map[string]int32)
+	p.routes = make(map[string]*routeTable)
+	p.logs = make(map[string]*exchangeLog)
+	p.captures = make(map[string]*harRecorder)
+	p.lazy = make(map[string]*lazyRoute)
+	p.transport.CloseIdleConnections()
+	p.h2cTransport.CloseIdleConnections()
 
-	return nil
+	return captures, nil
 }
 
 func (p *proxyAdapter) AddRoute(host string, localPort int32) {
@@ -109,18 +280,219 @@ func (p *proxyAdapter) AddRoute(host string, localPort int32) {
 func (p *proxyAdapter) AddRoutes(routes map[string]int32) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	rootKey := PathMatch{Path: "/"}.Key()
 	for host, port := range routes {
-		p.routes[host] = port
+		protocol := ProtocolHTTP1
+		if table, ok := p.routes[host]; ok {
+			if existing, ok := table.get(rootKey); ok && len(existing.backends) == 1 {
+				if existing.backends[0].localPort == port {
+					continue
+				}
+				protocol = existing.backends[0].protocol
+			}
+		}
+		p.addMatchRouteLocked(host, PathMatch{Path: "/"}, []Backend{{LocalPort: port, Protocol: protocol, Weight: 1}})
 	}
 }
 
-func (p *proxyAdapter) RemoveRoute(host string) {
+func (p *proxyAdapter) AddUpstreamRoute(host string, upstream *Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addMatchRouteLocked(host, PathMatch{Path: "/"}, []Backend{{Protocol: ProtocolHTTP1, Weight: 1, Upstream: upstream}})
+}
+
+func (p *proxyAdapter) AddPathRoute(host, prefix string, localPort int32, protocol Protocol) {
+	p.AddMatchRoute(host, PathMatch{Path: prefix}, []Backend{{LocalPort: localPort, Protocol: protocol, Weight: 1}})
+}
+
+func (p *proxyAdapter) AddMatchRoute(host string, match PathMatch, backends []Backend) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.addMatchRouteLocked(host, match, backends)
+}
+
+func (p *proxyAdapter) addMatchRouteLocked(host string, match PathMatch, backends []Backend) error {
+	newRoute, err := p.newRoute(host, match, backends)
+	if err != nil {
+		return err
+	}
+
+	table, ok := p.routes[host]
+	if !ok {
+		table = &routeTable{}
+		p.routes[host] = table
+	}
+	table.set(newRoute)
+
+	if _, ok := p.logs[host]; !ok {
+		p.logs[host] = newExchangeLog(p.options.Inspector.Capacity)
+	}
+	p.startAutoCaptureLocked(host)
+	return nil
+}
+
+func (p *proxyAdapter) RemovePathRoute(host, prefix string) {
+	p.RemoveMatchRoute(host, PathMatch{Path: prefix})
+}
+
+func (p *proxyAdapter) RemoveMatchRoute(host string, match PathMatch) {
+	p.mu.Lock()
+	table, ok := p.routes[host]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	table.remove(match.Key())
+	var recorder *harRecorder
+	if table.empty() {
+		recorder = p.removeHostLocked(host)
+	}
+	p.mu.Unlock()
+
+	if recorder != nil {
+		recorder.close()
+	}
+}
+
+func (p *proxyAdapter) RemoveRoute(host string) {
+	p.mu.Lock()
+	recorder := p.removeHostLocked(host)
+	p.mu.Unlock()
+
+	if recorder != nil {
+		recorder.close()
+	}
+}
+
+func (p *proxyAdapter) removeHostLocked(host string) *harRecorder {
+	recorder := p.captures[host]
 	delete(p.routes, host)
+	delete(p.logs, host)
+	delete(p.captures, host)
+	delete(p.lazy, host)
+	return recorder
+}
+
+func (p *proxyAdapter) SetRouteProtocol(host string, protocol Protocol) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	table, ok := p.routes[host]
+	if !ok {
+		if _, lazy := p.lazy[host]; lazy {
+			return nil
+		}
+		return fmt.Errorf("no route found for host: %s", host)
+	}
+	for _, existing := range table.routes {
+		backends := make([]Backend, 0, len(existing.backends))
+		changed := false
+		for _, b := range existing.backends {
+			backends = append(backends, Backend{LocalPort: b.localPort, Protocol: protocol, Weight: b.weight, Upstream: b.upstream, Lazy: b.lazy})
+			changed = changed || b.protocol != protocol
+		}
+		if !changed {
+			continue
+		}
+
+		updated, err := p.newRoute(host, existing.match, backends)
+		if err != nil {
+			return err
+		}
+		table.set(updated)
+	}
+	return nil
+}
+
+func (p *proxyAdapter) newRoute(host string, match PathMatch, backends []Backend) (*route, error) {
+	match.Path = NormalizePath(match.Path, match.Exact)
+	headers, err := newHeaderMatchers(match.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := findRouteRule(p.options.Rules, host)
+	r := &route{
+		key:     match.Key(),
+		match:   match,
+		headers: headers,
+		rule:    rule,
+	}
+	for _, b := range backends {
+		if b.Weight <= 0 {
+			continue
+		}
+		r.backends = append(r.backends, &backend{
+			localPort: b.LocalPort,
+			protocol:  b.Protocol,
+			weight:    b.Weight,
+			upstream:  b.Upstream,
+			lazy:      b.Lazy,
+			proxy:     p.newReverseProxy(b, rule),
+		})
+		r.totalWeight += b.Weight
+	}
+	return r, nil
 }
 
-func HandleProxyRequest(routes map[string]int32, mu *sync.RWMutex, w http.ResponseWriter, r *http.Request) {
+func (p *proxyAdapter) newReverseProxy(b Backend, rule *RouteRule) *httputil.ReverseProxy {
+	if b.Upstream != nil {
+		return p.newUpstreamProxy(b.Upstream, rule)
+	}
+	if b.Lazy != "" {
+		return p.newLazyProxy(b, rule)
+	}
+
+	targetURL := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("localhost:%d", b.LocalPort),
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.Transport = p.transportFor(b.Protocol)
+	configureProtocol(proxy, b.Protocol)
+	applyRouteRule(proxy, rule)
+	return proxy
+}
+
+func (p *proxyAdapter) newUpstreamProxy(upstream *Upstream, rule *RouteRule) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(upstream.URL)
+	proxy.Transport = upstream.Transport
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = upstream.URL.Host
+	}
+	applyRouteRule(proxy, rule)
+	return proxy
+}
+
+func (p *proxyAdapter) newLazyProxy(b Backend, rule *RouteRule) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: b.Lazy})
+	proxy.Transport = &lazyTransport{adapter: p, host: b.Lazy, base: p.transportFor(b.Protocol)}
+	configureProtocol(proxy, b.Protocol)
+	applyRouteRule(proxy, rule)
+	return proxy
+}
+
+func applyRouteRule(proxy *httputil.ReverseProxy, rule *RouteRule) {
+	if rule == nil {
+		return
+	}
+
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		rule.applyRequest(req)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		rule.applyResponse(resp)
+		return nil
+	}
+}
+
+func (p *proxyAdapter) HandleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	hostWithPort := r.Host
 	host := hostWithPort
 	if strings.Contains(hostWithPort, ":") {
@@ -128,24 +500,59 @@ func HandleProxyRequest(routes map[string]int32, mu *sync.RWMutex, w http.Respon
 		host = parts[0]
 	}
 
-	mu.RLock()
-	localPort, exists := routes[hostWithPort]
-	if !exists {
-		localPort, exists = routes[host]
+	routeHost := hostWithPort
+	p.mu.RLock()
+	table, exists := p.routes[routeHost]
+	lazy := p.lazy[routeHost]
+	if !exists && lazy == nil {
+		routeHost = host
+		table, exists = p.routes[routeHost]
+		lazy = p.lazy[routeHost]
+	}
+	if lazy != nil {
+		lazy.inFlight.Add(1)
+		lazy.touch()
+	}
+	p.mu.RUnlock()
+
+	if lazy != nil {
+		defer func() {
+			lazy.touch()
+			lazy.inFlight.Add(-1)
+		}()
+
+		if !exists {
+			var err error
+			table, err = p.activateLazy(routeHost, lazy)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("start tunnel for host %s: %v", routeHost, err), http.StatusBadGateway)
+				return
+			}
+			exists = true
+		}
 	}
-	mu.RUnlock()
+
+	p.mu.RLock()
+	var target *route
+	if exists {
+		target = table.match(r)
+	}
+	log := p.logs[routeHost]
+	har := p.captures[routeHost]
+	p.mu.RUnlock()
 
 	if !exists {
 		http.Error(w, fmt.Sprintf("no route found for host: %s", hostWithPort), http.StatusNotFound)
 		return
 	}
-
-	targetURL, err := url.Parse(fmt.Sprintf("http://localhost:%d", localPort))
-	if err != nil {
-		http.Error(w, fmt.Sprintf("invalid target URL: %v", err), http.StatusInternalServerError)
+	if target == nil {
+		http.Error(w, fmt.Sprintf("no route found for path %s on host: %s", r.URL.Path, hostWithPort), http.StatusNotFound)
 		return
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	proxy.ServeHTTP(w, r)
+	if log == nil {
+		target.ServeHTTP(w, r)
+		return
+	}
+	p.inspector.serve(log, har, routeHost, target, w, r)
 }
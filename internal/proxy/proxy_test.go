@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func newBenchmarkBackend(b *testing.B) (*atomic.Int64, int32) {
+	b.Helper()
+
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	b.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &conns, int32(port)
+}
+
+func reportConnections(b *testing.B, conns *atomic.Int64) {
+	b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+}
+
+func serveBenchmarkRequest(b *testing.B, handler http.Handler, host string) {
+	req := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		b.Errorf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func BenchmarkPerRequestReverseProxy(b *testing.B) {
+	conns, port := newBenchmarkBackend(b)
+	target := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", port)}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		transport := newTransport()
+		defer transport.CloseIdleConnections()
+
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = transport
+		proxy.ServeHTTP(w, r)
+	})
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			serveBenchmarkRequest(b, handler, "svc.ns")
+		}
+	})
+	reportConnections(b, conns)
+}
+
+func BenchmarkCachedRouteReverseProxy(b *testing.B) {
+	conns, port := newBenchmarkBackend(b)
+	p := NewProxyAdapter(nil, Options{}).(*proxyAdapter)
+	defer p.transport.CloseIdleConnections()
+	p.AddRoute("svc.ns", port)
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			serveBenchmarkRequest(b, http.HandlerFunc(p.HandleProxyRequest), "svc.ns")
+		}
+	})
+	reportConnections(b, conns)
+}
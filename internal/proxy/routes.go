@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type HeaderMatch struct {
+	Name  string
+	Value string
+	Regex bool
+}
+
+type PathMatch struct {
+	Path    string
+	Exact   bool
+	Headers []HeaderMatch
+}
+
+type Backend struct {
+	LocalPort int32
+	Protocol  Protocol
+	Weight    int32
+	Upstream  *Upstream
+	Lazy      string
+}
+
+type Upstream struct {
+	URL       *url.URL
+	Transport http.RoundTripper
+}
+
+func (m PathMatch) Key() string {
+	kind := "prefix"
+	if m.Exact {
+		kind = "exact"
+	}
+
+	headers := make([]string, 0, len(m.Headers))
+	for _, header := range m.Headers {
+		op := "="
+		if header.Regex {
+			op = "~"
+		}
+		headers = append(headers, http.CanonicalHeaderKey(header.Name)+op+header.Value)
+	}
+	sort.Strings(headers)
+
+	key := kind + ":" + NormalizePath(m.Path, m.Exact)
+	if len(headers) > 0 {
+		key += " " + strings.Join(headers, ",")
+	}
+	return key
+}
+
+type headerMatcher struct {
+	name  string
+	value string
+	regex *regexp.Regexp
+}
+
+func newHeaderMatchers(headers []HeaderMatch) ([]headerMatcher, error) {
+	matchers := make([]headerMatcher, 0, len(headers))
+	for _, header := range headers {
+		matcher := headerMatcher{name: header.Name, value: header.Value}
+		if header.Regex {
+			regex, err := regexp.Compile(header.Value)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %w", header.Name, err)
+			}
+			matcher.regex = regex
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+func (m headerMatcher) matches(headers http.Header) bool {
+	value := headers.Get(m.name)
+	if m.regex != nil {
+		return m.regex.MatchString(value)
+	}
+	return value == m.value
+}
+
+type routeTable struct {
+	routes []*route
+}
+
+func NormalizePathPrefix(prefix string) string {
+	if prefix == "" || prefix == "/" {
+		return "/"
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return strings.TrimSuffix(prefix, "/")
+}
+
+func NormalizePath(path string, exact bool) string {
+	if !exact {
+		return NormalizePathPrefix(path)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+func pathMatchesPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func (r *route) matches(req *http.Request) bool {
+	if r.match.Exact {
+		if req.URL.Path != r.match.Path {
+			return false
+		}
+	} else if !pathMatchesPrefix(req.URL.Path, r.match.Path) {
+		return false
+	}
+
+	for _, matcher := range r.headers {
+		if !matcher.matches(req.Header) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *route) pick() *backend {
+	if r.totalWeight <= 0 {
+		return nil
+	}
+	if len(r.backends) == 1 {
+		return r.backends[0]
+	}
+
+	n := rand.Int32N(r.totalWeight)
+	for _, b := range r.backends {
+		if n < b.weight {
+			return b
+		}
+		n -= b.weight
+	}
+	return nil
+}
+
+func (t *routeTable) match(req *http.Request) *route {
+	for _, r := range t.routes {
+		if r.matches(req) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (t *routeTable) get(key string) (*route, bool) {
+	for _, r := range t.routes {
+		if r.key == key {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (t *routeTable) set(newRoute *route) {
+	for i, r := range t.routes {
+		if r.key == newRoute.key {
+			t.routes[i] = newRoute
+			return
+		}
+	}
+
+	t.routes = append(t.routes, newRoute)
+	sort.SliceStable(t.routes, func(i, j int) bool {
+		a, b := t.routes[i].match, t.routes[j].match
+		if a.Exact != b.Exact {
+			return a.Exact
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		return len(a.Headers) > len(b.Headers)
+	})
+}
+
+func (t *routeTable) remove(key string) bool {
+	for i, r := range t.routes {
+		if r.key == key {
+			t.routes = append(t.routes[:i], t.routes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *routeTable) empty() bool {
+	return len(t.routes) == 0
+}
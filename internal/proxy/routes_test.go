@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestUpstreamRouteProxiesThroughServicePath(t *testing.T) {
+	type seen struct {
+		host, path, query string
+	}
+	requests := make(chan seen, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- seen{host: r.Host, path: r.URL.Path, query: r.URL.RawQuery}
+	}))
+	defer server.Close()
+
+	upstreamURL, err := url.Parse(server.URL + "/api/v1/namespaces/default/services/api:80/proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProxyAdapter(nil, Options{}).(*proxyAdapter)
+	p.AddUpstreamRoute("api.default", &Upstream{URL: upstreamURL, Transport: http.DefaultTransport})
+
+	rec := httptest.NewRecorder()
+	p.HandleProxyRequest(rec, httptest.NewRequest(http.MethodGet, "http://api.default/users?page=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	got := <-requests
+	want := seen{host: upstreamURL.Host, path: "/api/v1/namespaces/default/services/api:80/proxy/users", query: "page=2"}
+	if got != want {
+		t.Errorf("upstream saw %+v, want %+v", got, want)
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		exact bool
+		want  string
+	}{
+		{path: "", want: "/"},
+		{path: "/", want: "/"},
+		{path: "api", want: "/api"},
+		{path: "/api/", want: "/api"},
+		{path: "/api/v1", want: "/api/v1"},
+		{path: "api/", exact: true, want: "/api/"},
+		{path: "/healthz", exact: true, want: "/healthz"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizePath(tt.path, tt.exact); got != tt.want {
+			t.Errorf("NormalizePath(%q, %v) = %q, want %q", tt.path, tt.exact, got, tt.want)
+		}
+	}
+}
+
+func TestPathMatchesPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{path: "/anything", prefix: "/", want: true},
+		{path: "/api", prefix: "/api", want: true},
+		{path: "/api/users", prefix: "/api", want: true},
+		{path: "/apix", prefix: "/api", want: false},
+		{path: "/", prefix: "/api", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := pathMatchesPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("pathMatchesPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
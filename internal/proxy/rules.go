@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+type HeaderRules struct {
+	Add    map[string]string
+	Remove []string
+}
+
+type RouteRule struct {
+	Host            string
+	RequestHeaders  HeaderRules
+	ResponseHeaders HeaderRules
+	HostRewrite     string
+	StripPrefix     string
+	AddPrefix       string
+	CORS            bool
+}
+
+func findRouteRule(rules []RouteRule, host string) *RouteRule {
+	for i := range rules {
+		if rules[i].Host == host {
+			return &rules[i]
+		}
+	}
+	for i := range rules {
+		if matched, err := path.Match(rules[i].Host, host); err == nil && matched {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func (h HeaderRules) apply(headers http.Header) {
+	for _, name := range h.Remove {
+		headers.Del(name)
+	}
+	for name, value := range h.Add {
+		headers.Add(name, value)
+	}
+}
+
+func (r *RouteRule) applyRequest(req *http.Request) {
+	if r.StripPrefix != "" {
+		req.URL.Path = stripPathPrefix(req.URL.Path, r.StripPrefix)
+		req.URL.RawPath = ""
+	}
+	if r.AddPrefix != "" {
+		req.URL.Path = rewritePath(strings.TrimSuffix(r.AddPrefix, "/") + rewritePath(req.URL.Path))
+		req.URL.RawPath = ""
+	}
+	if r.HostRewrite != "" {
+		req.Host = r.HostRewrite
+	}
+	r.RequestHeaders.apply(req.Header)
+}
+
+func (r *RouteRule) applyResponse(resp *http.Response) {
+	r.ResponseHeaders.apply(resp.Header)
+	if r.CORS && resp.Request != nil {
+		writeCORSHeaders(resp.Header, resp.Request.Header)
+	}
+}
+
+func (r *RouteRule) handlePreflight(w http.ResponseWriter, req *http.Request) bool {
+	if !r.CORS || req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	writeCORSHeaders(w.Header(), req.Header)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func stripPathPrefix(p, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if p == prefix {
+		return "/"
+	}
+	if strings.HasPrefix(p, prefix+"/") {
+		return strings.TrimPrefix(p, prefix)
+	}
+	return p
+}
+
+func rewritePath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+func writeCORSHeaders(headers http.Header, requestHeaders http.Header) {
+	exposed := exposedHeaders(headers)
+	origin := requestHeaders.Get("Origin")
+	if origin == "" {
+		headers.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		headers.Set("Access-Control-Allow-Origin", origin)
+		headers.Set("Access-Control-Allow-Credentials", "true")
+		headers.Add("Vary", "Origin")
+	}
+
+	methods := requestHeaders.Get("Access-Control-Request-Method")
+	if methods == "" {
+		methods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	}
+	headers.Set("Access-Control-Allow-Methods", methods)
+
+	if requested := requestHeaders.Get("Access-Control-Request-Headers"); requested != "" {
+		headers.Set("Access-Control-Allow-Headers", requested)
+		headers.Add("Vary", "Access-Control-Request-Headers")
+	} else if origin == "" {
+		headers.Set("Access-Control-Allow-Headers", "*")
+	}
+
+	if origin == "" {
+		headers.Set("Access-Control-Expose-Headers", "*")
+	} else if len(exposed) > 0 {
+		headers.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+	}
+	headers.Set("Access-Control-Max-Age", "600")
+}
+
+func exposedHeaders(headers http.Header) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if strings.HasPrefix(name, "Access-Control-") || name == "Vary" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}